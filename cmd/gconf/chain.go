@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/miratronix/gconf"
+	"github.com/miratronix/gconf/internal"
+)
+
+// chain defines the loader chain a command builds its configuration from
+type chain struct {
+	files          stringList
+	environment    string
+	separator      string
+	lowerCase      bool
+	keyFile        string
+	keyEnvironment string
+	interpolate    bool
+	fileSecrets    bool
+	arguments      []string
+}
+
+// stringList defines a flag that can be repeated
+type stringList []string
+
+// String formats the list for flag usage
+func (list *stringList) String() string {
+	return strings.Join(*list, ", ")
+}
+
+// Set adds a value to the list
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// chainFlags adds the flags that configure the loader chain to the supplied flag set
+func chainFlags(flagSet *flag.FlagSet) *chain {
+	commandChain := &chain{}
+	flagSet.Var(&commandChain.files, "file", "A JSON or YAML file to load, repeated in priority order")
+	flagSet.StringVar(&commandChain.environment, "env-prefix", "", "Load environment variables with this prefix")
+	flagSet.StringVar(&commandChain.separator, "separator", "__", "The separator for nested keys in arguments and variables")
+	flagSet.BoolVar(&commandChain.lowerCase, "env-lowercase", false, "Lower-case environment variable names")
+	flagSet.StringVar(&commandChain.keyFile, "key-file", "", "Decrypt encrypted values in files with the keys in this file")
+	flagSet.StringVar(&commandChain.keyEnvironment, "key-env", "", "Decrypt encrypted values in files with the keys in this variable")
+	flagSet.BoolVar(&commandChain.interpolate, "interpolate", false, "Resolve ${...} references in values")
+	flagSet.BoolVar(&commandChain.fileSecrets, "file-secrets", false, "Resolve file:// secret references")
+	return commandChain
+}
+
+// load builds a configuration from the chain with the supplied extra files, such as the files a command compares.
+// Arguments after `--` have the highest priority, then environment variables, then the extra files and finally the
+// --file files, in order
+func (commandChain *chain) load(extraFiles []string, options ...internal.Option) (*internal.Config, error) {
+	if commandChain.interpolate {
+		options = append(options, gconf.WithInterpolation())
+	}
+	if commandChain.fileSecrets {
+		options = append(options, gconf.WithFileSecrets())
+	}
+	config := gconf.New(options...)
+
+	loaders := []internal.Loader{}
+	if len(commandChain.arguments) > 0 {
+		loaders = append(loaders, gconf.Arguments(commandChain.separator, "").WithArguments(commandChain.arguments))
+	}
+	if len(commandChain.environment) > 0 {
+		loaders = append(loaders, gconf.Environment(commandChain.lowerCase, commandChain.separator, commandChain.environment))
+	}
+	for _, filePath := range append(extraFiles[:len(extraFiles):len(extraFiles)], commandChain.files...) {
+		loader, err := commandChain.fileLoader(filePath)
+		if err != nil {
+			return nil, err
+		}
+		loaders = append(loaders, loader)
+	}
+
+	for _, loader := range loaders {
+		err := config.TryUse(loader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// fileLoader creates the loader for a file based on its extension, decrypting its values if keys were supplied
+func (commandChain *chain) fileLoader(filePath string) (internal.Loader, error) {
+	var loader internal.Loader
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		loader = gconf.JSONFile(filePath, true)
+	case ".yaml", ".yml":
+		loader = gconf.YAMLFile(filePath, true)
+	default:
+		return nil, fmt.Errorf("can't load '%s': only JSON and YAML files can be loaded", filePath)
+	}
+
+	switch {
+	case len(commandChain.keyFile) > 0:
+		return gconf.Decrypted(loader, gconf.KeyFromFile(commandChain.keyFile)), nil
+	case len(commandChain.keyEnvironment) > 0:
+		return gconf.Decrypted(loader, gconf.KeyFromEnvironment(commandChain.keyEnvironment)), nil
+	default:
+		return loader, nil
+	}
+}
+
+// formatOf returns the format of a file based on its extension
+func formatOf(filePath string) (internal.Format, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		return gconf.FormatJSON, nil
+	case ".yaml", ".yml":
+		return gconf.FormatYAML, nil
+	case ".toml":
+		return gconf.FormatTOML, nil
+	case ".env":
+		return gconf.FormatDotenv, nil
+	case ".properties":
+		return gconf.FormatProperties, nil
+	default:
+		return "", fmt.Errorf("can't write '%s': unknown format, use .json, .yaml, .toml, .env or .properties", filePath)
+	}
+}
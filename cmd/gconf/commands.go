@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miratronix/gconf"
+	"gopkg.in/yaml.v3"
+)
+
+// getFlags defines the get command, which prints the value of a key. Scalars are printed as-is and maps and slices
+// as YAML
+func getFlags(*flag.FlagSet) func(*environment, []string) error {
+	return func(env *environment, arguments []string) error {
+		config, err := env.chain.load(nil)
+		if err != nil {
+			return err
+		}
+
+		value, err := config.Get(arguments[0])
+		if err != nil {
+			return err
+		}
+
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			encoded, err := yaml.Marshal(gconf.PrintableValue(value))
+			if err != nil {
+				return err
+			}
+			_, err = env.stdout.Write(encoded)
+			return err
+		default:
+			_, err = fmt.Fprintln(env.stdout, gconf.PrintableValue(value))
+			return err
+		}
+	}
+}
+
+// dumpFlags defines the dump command, which prints the merged configuration with sensitive values redacted
+func dumpFlags(flagSet *flag.FlagSet) func(*environment, []string) error {
+	format := flagSet.String("format", string(gconf.FormatYAML), "The format to print in: json, yaml, toml, env or properties")
+	return func(env *environment, _ []string) error {
+		config, err := env.chain.load(nil)
+		if err != nil {
+			return err
+		}
+
+		dumped, err := config.Dump(gconf.Format(*format))
+		if err != nil {
+			return err
+		}
+		_, err = env.stdout.Write(dumped)
+		return err
+	}
+}
+
+// explainFlags defines the explain command, which prints where the value of a key came from
+func explainFlags(*flag.FlagSet) func(*environment, []string) error {
+	return func(env *environment, arguments []string) error {
+		config, err := env.chain.load(nil)
+		if err != nil {
+			return err
+		}
+
+		explanation, err := config.Explain(arguments[0])
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(env.stdout, explanation)
+		return err
+	}
+}
+
+// validateFlags defines the validate command, which prints every unresolved reference and schema violation on its own
+// line
+func validateFlags(flagSet *flag.FlagSet) func(*environment, []string) error {
+	schemaFile := flagSet.String("schema", "", "A JSON Schema file to validate the configuration against")
+	return func(env *environment, _ []string) error {
+		config, err := env.chain.load(nil)
+		if err != nil {
+			return err
+		}
+
+		err = config.Validate()
+		if err == nil && len(*schemaFile) > 0 {
+			schema, schemaErr := gconf.LoadSchemaFile(*schemaFile)
+			if schemaErr != nil {
+				return schemaErr
+			}
+			err = config.ValidateSchema(schema)
+		}
+
+		switch typedErr := err.(type) {
+		case nil:
+			_, err = fmt.Fprintln(env.stdout, "configuration is valid")
+			return err
+		case gconf.InterpolationErrors:
+			for _, interpolationErr := range typedErr {
+				fmt.Fprintln(env.stderr, interpolationErr)
+			}
+			return errFailed
+		case gconf.SchemaErrors:
+			for _, schemaErr := range typedErr {
+				fmt.Fprintln(env.stderr, schemaErr)
+			}
+			return errFailed
+		default:
+			return err
+		}
+	}
+}
+
+// diffFlags defines the diff command, which loads the chain once with each file and prints the keys that differ.
+// Values are compared unredacted but printed redacted, so a changed secret shows up without being revealed
+func diffFlags(*flag.FlagSet) func(*environment, []string) error {
+	return func(env *environment, arguments []string) error {
+		before, beforeDisplay, err := env.chain.properties(arguments[0])
+		if err != nil {
+			return err
+		}
+		after, afterDisplay, err := env.chain.properties(arguments[1])
+		if err != nil {
+			return err
+		}
+
+		keys := map[string]bool{}
+		for key := range before {
+			keys[key] = true
+		}
+		for key := range after {
+			keys[key] = true
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+
+		different := false
+		for _, key := range sortedKeys {
+			beforeValue, inBefore := before[key]
+			afterValue, inAfter := after[key]
+			if inBefore == inAfter && beforeValue == afterValue {
+				continue
+			}
+
+			different = true
+			if inBefore {
+				fmt.Fprintf(env.stdout, "- %s=%s\n", key, beforeDisplay[key])
+			}
+			if inAfter {
+				fmt.Fprintf(env.stdout, "+ %s=%s\n", key, afterDisplay[key])
+			}
+		}
+
+		if different {
+			return errFailed
+		}
+		return nil
+	}
+}
+
+// convertFlags defines the convert command, which writes the configuration loaded with the input file in the format
+// of the output file's extension
+func convertFlags(*flag.FlagSet) func(*environment, []string) error {
+	return func(env *environment, arguments []string) error {
+		format, err := formatOf(arguments[1])
+		if err != nil {
+			return err
+		}
+
+		config, err := env.chain.load(arguments[:1])
+		if err != nil {
+			return err
+		}
+		return config.WriteFile(arguments[1], format)
+	}
+}
+
+// properties loads the chain with the supplied file and returns its `key=value` lines as maps, once with the real
+// values and once with sensitive values redacted. The real values are interpolated, so that a changed reference shows
+// up as a difference in every key that uses it
+func (commandChain *chain) properties(filePath string) (map[string]string, map[string]string, error) {
+	config, err := commandChain.load([]string{filePath})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Write would write the references as they were loaded, so write the interpolated values from a plain copy
+	interpolated := gconf.New()
+	interpolated.Use(gconf.Map(config.Map()))
+
+	buffer := &bytes.Buffer{}
+	err = interpolated.Write(buffer, gconf.FormatProperties)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dumped, err := config.Dump(gconf.FormatProperties)
+	if err != nil {
+		return nil, nil, err
+	}
+	return propertyMap(buffer.Bytes()), propertyMap(dumped), nil
+}
+
+// propertyMap parses `key=value` lines into a map
+func propertyMap(lines []byte) map[string]string {
+	properties := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSuffix(string(lines), "\n"), "\n") {
+		key, value, found := strings.Cut(line, "=")
+		if found {
+			properties[key] = value
+		}
+	}
+	return properties
+}
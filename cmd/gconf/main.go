@@ -0,0 +1,175 @@
+// Command gconf shows the configuration a service built on gconf will see, without starting the service. Every command
+// builds the same loader chain the library does, from arguments, environment variables and files
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// errFailed is returned by commands that have already written their failure, such as validate and diff, so that only
+// the exit status has to be set
+var errFailed = errors.New("failed")
+
+// errUsage is returned for invalid command lines, after the usage has been written
+var errUsage = errors.New("usage")
+
+// command defines a gconf sub-command
+type command struct {
+	usage       string
+	description string
+	arguments   int
+	flags       func(flagSet *flag.FlagSet) func(*environment, []string) error
+}
+
+// environment defines what a command runs with: its output streams and the loader chain from its flags
+type environment struct {
+	stdout io.Writer
+	stderr io.Writer
+	chain  *chain
+}
+
+// commands defines the available sub-commands, by name
+var commands = map[string]command{
+	"get": {
+		usage:       "get [flags] <key>",
+		description: "Print the value of a key",
+		arguments:   1,
+		flags:       getFlags,
+	},
+	"dump": {
+		usage:       "dump [flags]",
+		description: "Print the merged configuration, with sensitive values redacted",
+		flags:       dumpFlags,
+	},
+	"explain": {
+		usage:       "explain [flags] <key>",
+		description: "Print where the value of a key came from, and the values it shadowed",
+		arguments:   1,
+		flags:       explainFlags,
+	},
+	"validate": {
+		usage:       "validate [flags]",
+		description: "Check that every reference resolves, and that the configuration matches a JSON Schema",
+		flags:       validateFlags,
+	},
+	"diff": {
+		usage:       "diff [flags] <a> <b>",
+		description: "Print the keys that differ between the configuration with file a and with file b",
+		arguments:   2,
+		flags:       diffFlags,
+	},
+	"convert": {
+		usage:       "convert [flags] <in> <out>",
+		description: "Write the configuration loaded from a JSON or YAML file in the format of the output file",
+		arguments:   2,
+		flags:       convertFlags,
+	},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command in the supplied arguments, returning the exit status
+func run(arguments []string, stdout io.Writer, stderr io.Writer) int {
+	if len(arguments) == 0 || arguments[0] == "help" || arguments[0] == "-h" || arguments[0] == "--help" {
+		writeUsage(stderr)
+		return 2
+	}
+
+	name := arguments[0]
+	selected, found := commands[name]
+	if !found {
+		fmt.Fprintf(stderr, "gconf: unknown command '%s'\n\n", name)
+		writeUsage(stderr)
+		return 2
+	}
+
+	err := runCommand(name, selected, arguments[1:], stdout, stderr)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.Is(err, errFailed):
+		return 1
+	default:
+		fmt.Fprintf(stderr, "gconf %s: %v\n", name, err)
+		return 1
+	}
+}
+
+// runCommand parses the flags and arguments of a command and runs it
+func runCommand(name string, selected command, arguments []string, stdout io.Writer, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	flagSet.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: gconf %s [-- service arguments]\n\n%s.\n\nFlags:\n", selected.usage, selected.description)
+		flagSet.PrintDefaults()
+	}
+
+	commandChain := chainFlags(flagSet)
+	runSelected := selected.flags(flagSet)
+
+	positional, forwarded, err := parseFlags(flagSet, arguments)
+	if err != nil {
+		return errUsage
+	}
+	if len(positional) != selected.arguments {
+		fmt.Fprintf(stderr, "gconf %s: expected %d arguments, got %d\n", name, selected.arguments, len(positional))
+		flagSet.Usage()
+		return errUsage
+	}
+
+	commandChain.arguments = forwarded
+	return runSelected(&environment{stdout: stdout, stderr: stderr, chain: commandChain}, positional)
+}
+
+// parseFlags parses the flags in the supplied arguments, allowing them before and after the positional arguments.
+// Everything after `--` is returned separately, as the arguments to forward to the argument loader
+func parseFlags(flagSet *flag.FlagSet, arguments []string) ([]string, []string, error) {
+	forwarded := []string{}
+	for i, argument := range arguments {
+		if argument == "--" {
+			forwarded = arguments[i+1:]
+			arguments = arguments[:i]
+			break
+		}
+	}
+
+	var positional []string
+	for {
+		err := flagSet.Parse(arguments)
+		if err != nil {
+			return nil, nil, err
+		}
+		if flagSet.NArg() == 0 {
+			return positional, forwarded, nil
+		}
+		positional = append(positional, flagSet.Arg(0))
+		arguments = flagSet.Args()[1:]
+	}
+}
+
+// writeUsage writes the list of commands
+func writeUsage(writer io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	builder := strings.Builder{}
+	builder.WriteString("Usage: gconf <command> [flags] [arguments] [-- service arguments]\n\nCommands:\n")
+	for _, name := range names {
+		builder.WriteString(fmt.Sprintf("  %-32s %s\n", commands[name].usage, commands[name].description))
+	}
+	builder.WriteString("\nRun 'gconf <command> -h' for the flags of a command.\n")
+	fmt.Fprint(writer, builder.String())
+}
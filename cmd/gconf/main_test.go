@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miratronix/gconf"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// runCaptured runs a command line, returning the exit status and everything written to stdout and stderr
+func runCaptured(arguments ...string) (int, string, string) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	status := run(arguments, stdout, stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	directory := t.TempDir()
+	write := func(name string, contents string) string {
+		filePath := filepath.Join(directory, name)
+		err := os.WriteFile(filePath, []byte(contents), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return filePath
+	}
+
+	base := write("base.yaml", "server:\n  host: localhost\n  port: 8080\n  timeout: 1m30s\ndatabase:\n  password: hunter2\n")
+	local := write("local.json", `{"server": {"port": 9090}, "database": {"password": "letmein"}}`)
+	schema := write("schema.json", `{"type": "object", "properties": {"server": {"type": "object", "properties": {"port": {"type": "string"}}}}}`)
+
+	Convey("Prints usage and exits with 2 without a known command", t, func() {
+		status, _, stderr := runCaptured()
+		So(status, ShouldEqual, 2)
+		So(stderr, ShouldContainSubstring, "Commands:")
+
+		status, _, stderr = runCaptured("frobnicate")
+		So(status, ShouldEqual, 2)
+		So(stderr, ShouldContainSubstring, "unknown command 'frobnicate'")
+	})
+
+	Convey("Exits with 2 for the wrong number of arguments or unknown flags", t, func() {
+		status, _, stderr := runCaptured("get")
+		So(status, ShouldEqual, 2)
+		So(stderr, ShouldContainSubstring, "expected 1 arguments, got 0")
+
+		status, _, _ = runCaptured("dump", "--nope")
+		So(status, ShouldEqual, 2)
+	})
+
+	Convey("Gets scalar values, with the first file winning", t, func() {
+		status, stdout, _ := runCaptured("get", "--file", local, "--file", base, "server:port")
+		So(status, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "9090\n")
+
+		_, stdout, _ = runCaptured("get", "server:timeout", "--file", base)
+		So(stdout, ShouldEqual, "1m30s\n")
+	})
+
+	Convey("Gets maps as YAML", t, func() {
+		status, stdout, _ := runCaptured("get", "--file", base, "server")
+		So(status, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "host: localhost\nport: 8080\ntimeout: 1m30s\n")
+	})
+
+	Convey("Gives arguments after -- and environment variables priority over files", t, func() {
+		t.Setenv("GCONFTEST_SERVER__HOST", "example.com")
+
+		_, stdout, _ := runCaptured("get", "--file", base, "--env-prefix", "GCONFTEST_", "--env-lowercase", "server:host")
+		So(stdout, ShouldEqual, "example.com\n")
+
+		_, stdout, _ = runCaptured("get", "--file", base, "--env-prefix", "GCONFTEST_", "--env-lowercase", "server:host", "--", "--server__host=arguments")
+		So(stdout, ShouldEqual, "arguments\n")
+	})
+
+	Convey("Returns an error for missing keys and files that can't be loaded", t, func() {
+		status, _, stderr := runCaptured("get", "--file", base, "server:missing")
+		So(status, ShouldEqual, 1)
+		So(stderr, ShouldStartWith, "gconf get: ")
+
+		status, _, stderr = runCaptured("get", "--file", filepath.Join(directory, "config.toml"), "server")
+		So(status, ShouldEqual, 1)
+		So(stderr, ShouldContainSubstring, "only JSON and YAML files can be loaded")
+	})
+
+	Convey("Dumps the configuration with sensitive values redacted", t, func() {
+		status, stdout, _ := runCaptured("dump", "--file", base, "--format", "properties")
+		So(status, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "database:password=******\nserver:host=localhost\nserver:port=8080\nserver:timeout=1m30s\n")
+
+		status, _, stderr := runCaptured("dump", "--file", base, "--format", "xml")
+		So(status, ShouldEqual, 1)
+		So(stderr, ShouldContainSubstring, "unsupported format 'xml'")
+	})
+
+	Convey("Explains where values came from", t, func() {
+		status, stdout, _ := runCaptured("explain", "--file", local, "--file", base, "server:port")
+		So(status, ShouldEqual, 0)
+		So(stdout, ShouldContainSubstring, "server:port = 9090 from")
+		So(stdout, ShouldContainSubstring, "shadowed: 8080 from")
+	})
+
+	Convey("Validates the configuration", t, func() {
+		status, stdout, _ := runCaptured("validate", "--file", base)
+		So(status, ShouldEqual, 0)
+		So(stdout, ShouldEqual, "configuration is valid\n")
+
+		status, _, stderr := runCaptured("validate", "--file", base, "--schema", schema)
+		So(status, ShouldEqual, 1)
+		So(stderr, ShouldStartWith, "key 'server:port'")
+
+		references := write("references.yaml", "url: http://${server:missing}\n")
+		status, _, stderr = runCaptured("validate", "--interpolate", "--file", references)
+		So(status, ShouldEqual, 1)
+		So(stderr, ShouldStartWith, "failed to interpolate '${server:missing}' in key 'url'")
+	})
+
+	Convey("Prints the differences between two files, with sensitive values redacted", t, func() {
+		status, stdout, _ := runCaptured("diff", base, local)
+		So(status, ShouldEqual, 1)
+		So(stdout, ShouldEqual, `- database:password=******
++ database:password=******
+- server:host=localhost
+- server:port=8080
++ server:port=9090
+- server:timeout=1m30s
+`)
+
+		status, stdout, _ = runCaptured("diff", "--file", base, base, base)
+		So(status, ShouldEqual, 0)
+		So(stdout, ShouldBeEmpty)
+	})
+
+	Convey("Redacts secrets interpolated into keys that aren't sensitive when printing differences", t, func() {
+		before := write("before.yaml", "db:\n  password: hunter2\n  dsn: pg://u:${db:password}@h\n")
+		after := write("after.yaml", "db:\n  password: letmein\n  dsn: pg://u:${db:password}@h\n")
+
+		status, stdout, _ := runCaptured("diff", "--interpolate", before, after)
+		So(status, ShouldEqual, 1)
+		So(stdout, ShouldEqual, `- db:dsn=******
++ db:dsn=******
+- db:password=******
++ db:password=******
+`)
+	})
+
+	Convey("Converts files between formats", t, func() {
+		output := filepath.Join(directory, "converted.toml")
+		status, _, _ := runCaptured("convert", base, output)
+		So(status, ShouldEqual, 0)
+
+		converted, err := os.ReadFile(output)
+		So(err, ShouldBeNil)
+		So(string(converted), ShouldContainSubstring, "[server]\n  host = \"localhost\"")
+
+		output = filepath.Join(directory, "converted.json")
+		_, _, _ = runCaptured("convert", base, output)
+		reloaded := gconf.New()
+		reloaded.Use(gconf.JSONFile(output, true))
+		port, err := reloaded.GetInteger("server:port")
+		So(err, ShouldBeNil)
+		So(port, ShouldEqual, 8080)
+
+		status, _, stderr := runCaptured("convert", base, filepath.Join(directory, "converted.xml"))
+		So(status, ShouldEqual, 1)
+		So(stderr, ShouldContainSubstring, "unknown format")
+	})
+}
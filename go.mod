@@ -3,7 +3,10 @@ module github.com/miratronix/gconf
 go 1.18
 
 require (
+	filippo.io/age v1.0.0
+	github.com/BurntSushi/toml v1.3.2
 	github.com/mitchellh/mapstructure v1.5.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
 	github.com/smartystreets/goconvey v1.8.1
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -12,4 +15,6 @@ require (
 	github.com/gopherjs/gopherjs v1.17.2 // indirect
 	github.com/jtolds/gls v4.20.0+incompatible // indirect
 	github.com/smarty/assertions v1.15.0 // indirect
+	golang.org/x/crypto v0.0.0-20220214200702-86341886e292 // indirect
+	golang.org/x/sys v0.10.0 // indirect
 )
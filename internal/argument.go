@@ -10,6 +10,7 @@ type ArgumentLoader struct {
 	lowerCase bool
 	prefix    string
 	separator string
+	arguments []string
 }
 
 // NewArgumentLoader creates a new argument loader
@@ -20,14 +21,31 @@ func NewArgumentLoader(separator string, prefix string) *ArgumentLoader {
 	}
 }
 
-// Load loads environment variables into a configuration map
+// WithArguments makes the loader read the supplied arguments instead of the process's command line arguments
+func (loader *ArgumentLoader) WithArguments(arguments []string) *ArgumentLoader {
+	loader.arguments = arguments
+	return loader
+}
+
+// Load loads command line arguments into a configuration map
 func (loader *ArgumentLoader) Load() (map[string]interface{}, error) {
+	config, _, err := loader.loadWithNames()
+	return config, err
+}
+
+// loadWithNames loads command line arguments into a configuration map, along with a map of the argument each key was
+// loaded from
+func (loader *ArgumentLoader) loadWithNames() (map[string]interface{}, map[string]interface{}, error) {
+	if loader.arguments != nil {
+		return loader.parseArguments(loader.arguments)
+	}
 	return loader.parseArguments(os.Args[1:])
 }
 
-// parseArguments parses command line arguments into valid types
-func (loader *ArgumentLoader) parseArguments(args []string) (map[string]interface{}, error) {
+// parseArguments parses command line arguments into valid types, returning the argument names by key alongside them
+func (loader *ArgumentLoader) parseArguments(args []string) (map[string]interface{}, map[string]interface{}, error) {
 	config := map[string]interface{}{}
+	names := map[string]interface{}{}
 
 	for _, arg := range args {
 
@@ -65,9 +83,12 @@ func (loader *ArgumentLoader) parseArguments(args []string) (map[string]interfac
 		// Parse the value and add it to the final config map
 		_, err := set(config, separatedKey, parseString(value))
 		if err != nil {
-			return config, err
+			return config, names, err
 		}
+
+		// Record the argument name so the value's source can be reported
+		_, _ = set(names, separatedKey, parts[0])
 	}
 
-	return config, nil
+	return config, names, nil
 }
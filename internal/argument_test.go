@@ -10,7 +10,7 @@ func TestParseArguments(t *testing.T) {
 
 	Convey("Returns an empty map if there are no arguments", t, func() {
 		loader := NewArgumentLoader("", "")
-		result, err := loader.parseArguments([]string{})
+		result, _, err := loader.parseArguments([]string{})
 		So(result, ShouldBeEmpty)
 		So(err, ShouldBeNil)
 	})
@@ -19,25 +19,25 @@ func TestParseArguments(t *testing.T) {
 		loader := NewArgumentLoader("", "")
 
 		Convey("Ignores arguments not starting with '-' or '--'", func() {
-			result, err := loader.parseArguments([]string{"string=testing"})
+			result, _, err := loader.parseArguments([]string{"string=testing"})
 			So(result, ShouldBeEmpty)
 			So(err, ShouldBeNil)
 		})
 
 		Convey("Ignores arguments without an '='", func() {
-			result, err := loader.parseArguments([]string{"testing"})
+			result, _, err := loader.parseArguments([]string{"testing"})
 			So(result, ShouldBeEmpty)
 			So(err, ShouldBeNil)
 		})
 
 		Convey("Parses arguments starting with '-'", func() {
-			result, err := loader.parseArguments([]string{"-string=testing"})
+			result, _, err := loader.parseArguments([]string{"-string=testing"})
 			So(result, ShouldResemble, map[string]interface{}{"string": "testing"})
 			So(err, ShouldBeNil)
 		})
 
 		Convey("Parses arguments starting with '--'", func() {
-			result, err := loader.parseArguments([]string{"--string=testing"})
+			result, _, err := loader.parseArguments([]string{"--string=testing"})
 			So(result, ShouldResemble, map[string]interface{}{"string": "testing"})
 			So(err, ShouldBeNil)
 		})
@@ -47,13 +47,13 @@ func TestParseArguments(t *testing.T) {
 		loader := NewArgumentLoader("__", "")
 
 		Convey("Nests the configuration using the separator", func() {
-			result, err := loader.parseArguments([]string{"--map__string=testing"})
+			result, _, err := loader.parseArguments([]string{"--map__string=testing"})
 			So(result, ShouldResemble, map[string]interface{}{"map": map[string]interface{}{"string": "testing"}})
 			So(err, ShouldBeNil)
 		})
 
 		Convey("Fails when a nested option would override another option", func() {
-			result, err := loader.parseArguments([]string{"--test=testing", "--test__stuff=things"})
+			result, _, err := loader.parseArguments([]string{"--test=testing", "--test__stuff=things"})
 			So(result, ShouldResemble, map[string]interface{}{"test": "testing"})
 			So(err, ShouldNotBeNil)
 		})
@@ -63,15 +63,34 @@ func TestParseArguments(t *testing.T) {
 		loader := NewArgumentLoader("", "TEST")
 
 		Convey("Strips the prefix from the argument", func() {
-			result, err := loader.parseArguments([]string{"--TESTing=testing"})
+			result, _, err := loader.parseArguments([]string{"--TESTing=testing"})
 			So(result, ShouldResemble, map[string]interface{}{"ing": "testing"})
 			So(err, ShouldBeNil)
 		})
 
 		Convey("Ignores arguments without the prefix", func() {
-			result, err := loader.parseArguments([]string{"--notTesting=testing"})
+			result, _, err := loader.parseArguments([]string{"--notTesting=testing"})
 			So(result, ShouldBeEmpty)
 			So(err, ShouldBeNil)
 		})
 	})
+
+	Convey("Records the argument name of every key", t, func() {
+		loader := NewArgumentLoader("__", "")
+		_, names, err := loader.parseArguments([]string{"--db__host=localhost", "-port=80"})
+		So(names, ShouldResemble, map[string]interface{}{
+			"db":   map[string]interface{}{"host": "--db__host"},
+			"port": "-port",
+		})
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestArgumentLoad(t *testing.T) {
+
+	Convey("Loads the supplied arguments instead of the process's", t, func() {
+		result, err := NewArgumentLoader("__", "").WithArguments([]string{"--db__host=localhost", "ignored"}).Load()
+		So(result, ShouldResemble, map[string]interface{}{"db": map[string]interface{}{"host": "localhost"}})
+		So(err, ShouldBeNil)
+	})
 }
@@ -0,0 +1,35 @@
+package internal
+
+// Chain defines a chainable set of loaders that records loader failures instead of panicking
+type Chain struct {
+	config *Config
+	errors LoaderErrors
+}
+
+// Use adds a loader to the underlying configuration, recording the failure if the loader fails
+func (chain *Chain) Use(loader Loader) *Chain {
+	err := chain.config.use(loader)
+	if err != nil {
+		chain.errors = append(chain.errors, err)
+	}
+	return chain
+}
+
+// Config returns the configuration that the chain loads into
+func (chain *Chain) Config() *Config {
+	return chain.config
+}
+
+// Err returns a LoaderErrors containing every loader failure in the chain. If all the loaders succeeded, it returns an
+// InterpolationErrors containing every reference that the loaded configuration can't resolve, or nil if there are none
+func (chain *Chain) Err() error {
+	if len(chain.errors) > 0 {
+		return chain.errors
+	}
+
+	interpolationErrors := chain.config.current().interpolation
+	if len(interpolationErrors) > 0 {
+		return interpolationErrors
+	}
+	return nil
+}
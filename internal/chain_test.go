@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestChain(t *testing.T) {
+
+	Convey("Loads every loader into the configuration", t, func() {
+		config := NewConfig()
+		err := config.Chain().
+			Use(NewMapLoader(map[string]interface{}{"one": 1})).
+			Use(NewMapLoader(map[string]interface{}{"two": 2})).
+			Err()
+
+		So(err, ShouldBeNil)
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 1, "two": 2})
+	})
+
+	Convey("Collects every loader failure and keeps loading", t, func() {
+		config := NewConfig()
+		err := config.Chain().
+			Use(&failingLoader{err: errors.New("first")}).
+			Use(NewMapLoader(map[string]interface{}{"one": 1})).
+			Use(&failingLoader{err: errors.New("second")}).
+			Err()
+
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 1})
+		So(err, ShouldHaveSameTypeAs, LoaderErrors{})
+
+		loaderErrors := err.(LoaderErrors)
+		So(loaderErrors, ShouldHaveLength, 2)
+		So(loaderErrors[0].Index, ShouldEqual, 0)
+		So(loaderErrors[0].Err.Error(), ShouldEqual, "first")
+		So(loaderErrors[1].Index, ShouldEqual, 2)
+		So(loaderErrors[1].Err.Error(), ShouldEqual, "second")
+	})
+
+	Convey("Returns the references that can't be resolved once every loader succeeded", t, func() {
+		config := NewConfig(WithInterpolation())
+		err := config.Chain().
+			Use(NewMapLoader(map[string]interface{}{"url": "http://${host}"})).
+			Err()
+
+		So(err, ShouldHaveSameTypeAs, InterpolationErrors{})
+		So(err.(InterpolationErrors), ShouldHaveLength, 1)
+
+		err = config.Chain().
+			Use(NewMapLoader(map[string]interface{}{"host": "localhost"})).
+			Err()
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns the underlying configuration", t, func() {
+		config := NewConfig()
+		So(config.Chain().Config(), ShouldEqual, config)
+	})
+}
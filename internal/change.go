@@ -0,0 +1,73 @@
+package internal
+
+import "reflect"
+
+// ChangeHandler defines a function that's called with the old and new value of a changed key
+type ChangeHandler func(oldValue interface{}, newValue interface{})
+
+// subscription defines a change handler subscribed to a key
+type subscription struct {
+	keys    []string
+	fold    bool
+	handler ChangeHandler
+}
+
+// OnChange subscribes the handler to changes of the value at the supplied key, made by a reload or a Set. The key may
+// end in a `*` segment (e.g. `db:*`) to subscribe to every change below a prefix, in which case the handler receives the old and
+// new map at the prefix. A key of `*` subscribes to every change. Missing values are passed to the handler as nil.
+// Handlers are called on the goroutine that made the change. Returns a function that cancels the subscription
+func (config *Config) OnChange(key string, handler ChangeHandler) func() {
+	subscribed := &subscription{
+		keys:    subscriptionKeys(config.splitKey(key)),
+		fold:    config.options.caseInsensitive,
+		handler: handler,
+	}
+	config.mutex.Lock()
+	config.subscriptions = append(config.subscriptions[:len(config.subscriptions):len(config.subscriptions)], subscribed)
+	config.mutex.Unlock()
+
+	return func() {
+		config.mutex.Lock()
+		defer config.mutex.Unlock()
+
+		for i, existing := range config.subscriptions {
+			if existing == subscribed {
+				config.subscriptions = append(config.subscriptions[:i:i], config.subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notify calls every subscribed handler whose value differs between the old and new configuration maps, with copies of
+// the values so that handlers can't modify the configuration
+func notify(subscriptions []*subscription, oldMap map[string]interface{}, newMap map[string]interface{}) {
+	for _, subscribed := range subscriptions {
+		oldValue := valueAt(oldMap, subscribed.keys, subscribed.fold)
+		newValue := valueAt(newMap, subscribed.keys, subscribed.fold)
+		if !reflect.DeepEqual(oldValue, newValue) {
+			subscribed.handler(copyValue(oldValue), copyValue(newValue))
+		}
+	}
+}
+
+// subscriptionKeys removes the trailing wildcard from the split key of a prefix subscription
+func subscriptionKeys(keys []string) []string {
+	if keys[len(keys)-1] == "*" {
+		return keys[:len(keys)-1]
+	}
+	return keys
+}
+
+// valueAt returns the value at the supplied keys, the whole map if there are no keys, or nil if it doesn't exist
+func valueAt(m map[string]interface{}, keys []string, fold bool) interface{} {
+	if len(keys) == 0 {
+		return m
+	}
+
+	value, err := getPath(m, keys, fold)
+	if err != nil {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// change records a single call to a change handler
+type change struct {
+	oldValue interface{}
+	newValue interface{}
+}
+
+// recordChanges returns a change handler that appends every change to the supplied slice
+func recordChanges(changes *[]change) ChangeHandler {
+	return func(oldValue interface{}, newValue interface{}) {
+		*changes = append(*changes, change{oldValue: oldValue, newValue: newValue})
+	}
+}
+
+func TestOnChange(t *testing.T) {
+
+	Convey("Notifies subscribers when a reload changes their key", t, func() {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		So(os.WriteFile(path, []byte("log:\n  level: info\ndb:\n  host: a\n  port: 1"), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewYAMLFileLoader(path, false))
+
+		var levelChanges, dbChanges, hostChanges []change
+		config.OnChange("log:level", recordChanges(&levelChanges))
+		config.OnChange("db:*", recordChanges(&dbChanges))
+		config.OnChange("db:host", recordChanges(&hostChanges))
+
+		So(os.WriteFile(path, []byte("log:\n  level: debug\ndb:\n  host: a\n  port: 2"), 0644), ShouldBeNil)
+		So(config.Reload(), ShouldBeNil)
+
+		So(levelChanges, ShouldResemble, []change{{oldValue: "info", newValue: "debug"}})
+		So(dbChanges, ShouldResemble, []change{{
+			oldValue: map[string]interface{}{"host": "a", "port": 1},
+			newValue: map[string]interface{}{"host": "a", "port": 2},
+		}})
+		So(hostChanges, ShouldBeEmpty)
+	})
+
+	Convey("Doesn't notify subscribers when a reload doesn't change anything", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"one": 1}))
+
+		var changes []change
+		config.OnChange("*", recordChanges(&changes))
+		So(config.Reload(), ShouldBeNil)
+		So(changes, ShouldBeEmpty)
+	})
+
+	Convey("Notifies subscribers when a Set adds their key", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"db": map[string]interface{}{"host": "a"}}))
+
+		var portChanges, dbChanges, otherChanges []change
+		config.OnChange("db:port", recordChanges(&portChanges))
+		config.OnChange("db:*", recordChanges(&dbChanges))
+		config.OnChange("other", recordChanges(&otherChanges))
+
+		So(config.Set("db:port", 5432), ShouldBeNil)
+		So(portChanges, ShouldResemble, []change{{oldValue: nil, newValue: 5432}})
+		So(dbChanges, ShouldResemble, []change{{
+			oldValue: map[string]interface{}{"host": "a"},
+			newValue: map[string]interface{}{"host": "a", "port": 5432},
+		}})
+		So(otherChanges, ShouldBeEmpty)
+	})
+
+	Convey("Passes copies of the values that can't modify the configuration", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"db": map[string]interface{}{"host": "a"}}))
+
+		config.OnChange("db:*", func(oldValue interface{}, newValue interface{}) {
+			oldValue.(map[string]interface{})["host"] = "changed"
+			newValue.(map[string]interface{})["host"] = "changed"
+		})
+		So(config.Set("db:port", 5432), ShouldBeNil)
+		So(config.Map(), ShouldResemble, map[string]interface{}{"db": map[string]interface{}{"host": "a", "port": 5432}})
+	})
+
+	Convey("Stops notifying cancelled subscriptions", t, func() {
+		config := NewConfig()
+
+		var changes []change
+		cancel := config.OnChange("one", recordChanges(&changes))
+		cancel()
+		cancel()
+
+		So(config.Set("one", 1), ShouldBeNil)
+		So(changes, ShouldBeEmpty)
+	})
+}
+
+func TestSubscriptionKeys(t *testing.T) {
+
+	Convey("Keeps exact keys", t, func() {
+		So(subscriptionKeys([]string{"db", "host"}), ShouldResemble, []string{"db", "host"})
+	})
+
+	Convey("Removes the wildcard from prefix keys", t, func() {
+		So(subscriptionKeys([]string{"db", "*"}), ShouldResemble, []string{"db"})
+	})
+
+	Convey("Subscribes to the whole map with a lone wildcard", t, func() {
+		So(subscriptionKeys([]string{"*"}), ShouldBeEmpty)
+	})
+}
+
+func TestValueAt(t *testing.T) {
+	m := map[string]interface{}{"db": map[string]interface{}{"host": "a"}}
+
+	Convey("Returns the value at the keys", t, func() {
+		So(valueAt(m, []string{"db", "host"}, false), ShouldEqual, "a")
+	})
+
+	Convey("Returns the whole map when there are no keys", t, func() {
+		So(valueAt(m, []string{}, false), ShouldResemble, m)
+	})
+
+	Convey("Returns nil when the value doesn't exist", t, func() {
+		So(valueAt(m, []string{"db", "port"}, false), ShouldBeNil)
+	})
+}
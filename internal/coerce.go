@@ -0,0 +1,355 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Get gets a key from the loaded configuration, converting it to the requested type
+func Get[T any](config *Config, key string) (T, error) {
+	var result T
+
+	value, err := config.Get(key)
+	if err != nil {
+		return result, err
+	}
+
+	result, err = coerce[T](value)
+	if err != nil {
+		return result, fmt.Errorf("failed to read key '%s': %w", key, err)
+	}
+	return result, nil
+}
+
+// coerce converts the supplied value into the requested type
+func coerce[T any](value interface{}) (T, error) {
+	var result T
+
+	converted, err := coerceValue(value, reflect.TypeOf(&result).Elem())
+	if err != nil {
+		return result, err
+	}
+
+	reflect.ValueOf(&result).Elem().Set(converted)
+	return result, nil
+}
+
+// coerceValue converts the supplied value into the target type, failing if the conversion would lose data
+func coerceValue(value interface{}, target reflect.Type) (reflect.Value, error) {
+
+	// Nil can only be converted to the types that can hold it
+	if value == nil {
+		switch target.Kind() {
+		case reflect.Interface, reflect.Map, reflect.Slice, reflect.Pointer:
+			return reflect.Zero(target), nil
+		default:
+			return reflect.Value{}, fmt.Errorf("cannot convert nil to %s", target)
+		}
+	}
+
+	// If the value already has the correct type, there's nothing to convert
+	source := reflect.ValueOf(value)
+	if source.Type() == target {
+		return source, nil
+	}
+
+	// Some types have their own conversion rules
+	switch target {
+	case durationType:
+		return coerceDuration(source, target)
+	case timeType:
+		return coerceTime(source, target)
+	case byteSizeType:
+		return coerceByteSize(source, target)
+	}
+
+	switch target.Kind() {
+	case reflect.Interface:
+		if source.Type().Implements(target) {
+			return source.Convert(target), nil
+		}
+	case reflect.Bool:
+		return coerceBool(source, target)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return coerceInt(source, target)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return coerceUint(source, target)
+	case reflect.Float32, reflect.Float64:
+		return coerceFloat(source, target)
+	case reflect.String:
+		return coerceString(source, target)
+	case reflect.Slice:
+		return coerceSlice(source, target)
+	case reflect.Map:
+		return coerceMap(source, target)
+	}
+
+	return reflect.Value{}, conversionError(source, target, "unsupported conversion")
+}
+
+// coerceBool converts the supplied value into a boolean type
+func coerceBool(source reflect.Value, target reflect.Type) (reflect.Value, error) {
+	result := reflect.New(target).Elem()
+
+	switch source.Kind() {
+	case reflect.Bool:
+		result.SetBool(source.Bool())
+	case reflect.String:
+		boolValue, err := strconv.ParseBool(source.String())
+		if err != nil {
+			return reflect.Value{}, conversionError(source, target, "not a boolean")
+		}
+		result.SetBool(boolValue)
+	default:
+		return reflect.Value{}, conversionError(source, target, "unsupported conversion")
+	}
+
+	return result, nil
+}
+
+// coerceInt converts the supplied value into a signed integer type, failing if the value would be truncated or overflow
+func coerceInt(source reflect.Value, target reflect.Type) (reflect.Value, error) {
+	result := reflect.New(target).Elem()
+
+	var intValue int64
+	switch source.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		intValue = source.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if source.Uint() > math.MaxInt64 {
+			return reflect.Value{}, conversionError(source, target, "value overflows the target type")
+		}
+		intValue = int64(source.Uint())
+	case reflect.Float32, reflect.Float64:
+		floatValue, err := floatToInt(source.Float())
+		if err != nil {
+			return reflect.Value{}, conversionError(source, target, err.Error())
+		}
+		intValue = floatValue
+	case reflect.String:
+		parsed, err := parseInt(source.String())
+		if err != nil {
+			return reflect.Value{}, conversionError(source, target, err.Error())
+		}
+		intValue = parsed
+	default:
+		return reflect.Value{}, conversionError(source, target, "unsupported conversion")
+	}
+
+	if result.OverflowInt(intValue) {
+		return reflect.Value{}, conversionError(source, target, "value overflows the target type")
+	}
+
+	result.SetInt(intValue)
+	return result, nil
+}
+
+// coerceUint converts the supplied value into an unsigned integer type, failing if the value is negative, would be
+// truncated or would overflow
+func coerceUint(source reflect.Value, target reflect.Type) (reflect.Value, error) {
+	result := reflect.New(target).Elem()
+
+	// Uints can be copied over directly, everything else goes through a signed integer first
+	var uintValue uint64
+	switch source.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		uintValue = source.Uint()
+	case reflect.String:
+		parsed, err := strconv.ParseUint(source.String(), 10, 64)
+		if err == nil {
+			uintValue = parsed
+			break
+		}
+		fallthrough
+	default:
+		intValue, err := coerceInt(source, reflect.TypeOf(int64(0)))
+		if err != nil {
+			return reflect.Value{}, conversionError(source, target, err.(*ConversionError).Reason)
+		}
+		if intValue.Int() < 0 {
+			return reflect.Value{}, conversionError(source, target, "value is negative")
+		}
+		uintValue = uint64(intValue.Int())
+	}
+
+	if result.OverflowUint(uintValue) {
+		return reflect.Value{}, conversionError(source, target, "value overflows the target type")
+	}
+
+	result.SetUint(uintValue)
+	return result, nil
+}
+
+// coerceFloat converts the supplied value into a float type, failing if the value would lose precision or overflow
+func coerceFloat(source reflect.Value, target reflect.Type) (reflect.Value, error) {
+	result := reflect.New(target).Elem()
+
+	var floatValue float64
+	switch source.Kind() {
+	case reflect.Float32, reflect.Float64:
+		floatValue = source.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		floatValue = float64(source.Int())
+		if floatValue >= math.MaxInt64 || int64(floatValue) != source.Int() {
+			return reflect.Value{}, conversionError(source, target, "value would lose precision")
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		floatValue = float64(source.Uint())
+		if floatValue >= math.MaxUint64 || uint64(floatValue) != source.Uint() {
+			return reflect.Value{}, conversionError(source, target, "value would lose precision")
+		}
+	case reflect.String:
+		parsed, err := strconv.ParseFloat(source.String(), 64)
+		if err != nil {
+			return reflect.Value{}, conversionError(source, target, "not a number")
+		}
+		floatValue = parsed
+	default:
+		return reflect.Value{}, conversionError(source, target, "unsupported conversion")
+	}
+
+	if result.OverflowFloat(floatValue) {
+		return reflect.Value{}, conversionError(source, target, "value overflows the target type")
+	}
+
+	result.SetFloat(floatValue)
+	return result, nil
+}
+
+// coerceString converts the supplied value into a string type
+func coerceString(source reflect.Value, target reflect.Type) (reflect.Value, error) {
+	result := reflect.New(target).Elem()
+
+	// Prefer the value's own formatting (e.g. for durations)
+	stringer, isStringer := source.Interface().(fmt.Stringer)
+	if isStringer {
+		result.SetString(stringer.String())
+		return result, nil
+	}
+
+	switch source.Kind() {
+	case reflect.String:
+		result.SetString(source.String())
+	case reflect.Bool:
+		result.SetString(strconv.FormatBool(source.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result.SetString(strconv.FormatInt(source.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		result.SetString(strconv.FormatUint(source.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		result.SetString(strconv.FormatFloat(source.Float(), 'f', -1, source.Type().Bits()))
+	case reflect.Slice:
+		if source.Type().Elem().Kind() != reflect.Uint8 {
+			return reflect.Value{}, conversionError(source, target, "unsupported conversion")
+		}
+		result.SetString(string(source.Bytes()))
+	default:
+		return reflect.Value{}, conversionError(source, target, "unsupported conversion")
+	}
+
+	return result, nil
+}
+
+// coerceSlice converts the supplied slice or array into a slice type, converting every element
+func coerceSlice(source reflect.Value, target reflect.Type) (reflect.Value, error) {
+
+	// Strings can become byte slices
+	if source.Kind() == reflect.String && target.Elem().Kind() == reflect.Uint8 {
+		return source.Convert(target), nil
+	}
+
+	if source.Kind() != reflect.Slice && source.Kind() != reflect.Array {
+		return reflect.Value{}, conversionError(source, target, "not a slice")
+	}
+
+	result := reflect.MakeSlice(target, source.Len(), source.Len())
+	for i := 0; i < source.Len(); i++ {
+		element, err := coerceValue(source.Index(i).Interface(), target.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+		}
+		result.Index(i).Set(element)
+	}
+
+	return result, nil
+}
+
+// coerceMap converts the supplied map into a map type, converting every key and value
+func coerceMap(source reflect.Value, target reflect.Type) (reflect.Value, error) {
+	if source.Kind() != reflect.Map {
+		return reflect.Value{}, conversionError(source, target, "not a map")
+	}
+
+	// Sort the keys so that errors are deterministic
+	keys := source.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+
+	result := reflect.MakeMapWithSize(target, source.Len())
+	for _, key := range keys {
+		convertedKey, err := coerceValue(key.Interface(), target.Key())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("key '%v': %w", key.Interface(), err)
+		}
+
+		convertedValue, err := coerceValue(source.MapIndex(key).Interface(), target.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("value of key '%v': %w", key.Interface(), err)
+		}
+
+		result.SetMapIndex(convertedKey, convertedValue)
+	}
+
+	return result, nil
+}
+
+// floatToInt converts a float to an integer, failing if it has a fractional part or is out of range
+func floatToInt(value float64) (int64, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) || value != math.Trunc(value) {
+		return 0, fmt.Errorf("value would lose precision")
+	}
+	if value < math.MinInt64 || value >= math.MaxInt64 {
+		return 0, fmt.Errorf("value overflows the target type")
+	}
+	return int64(value), nil
+}
+
+// parseInt parses a string into an integer, accepting floats without a fractional part
+func parseInt(value string) (int64, error) {
+	intValue, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return intValue, nil
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("not a number")
+	}
+	return floatToInt(floatValue)
+}
+
+// ConversionError defines a value that couldn't be converted to the requested type
+type ConversionError struct {
+	Value  interface{}
+	Type   reflect.Type
+	Reason string
+}
+
+// Error formats the conversion error, including the value's type and the reason the conversion failed. The value
+// itself is left out since it may be a secret, and is available in the Value field
+func (err *ConversionError) Error() string {
+	return fmt.Sprintf("cannot convert %T value to %s: %s", err.Value, err.Type, err.Reason)
+}
+
+// conversionError creates an error describing a failed conversion of the source value to the target type
+func conversionError(source reflect.Value, target reflect.Type, reason string) error {
+	return &ConversionError{
+		Value:  source.Interface(),
+		Type:   target,
+		Reason: reason,
+	}
+}
@@ -0,0 +1,342 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetGeneric(t *testing.T) {
+	config := NewConfig()
+	config.Use(NewJSONFileLoader("../test/test.json", false))
+
+	Convey("Converts JSON numbers to integers", t, func() {
+		result, err := Get[int](config, "integer")
+		So(result, ShouldEqual, 10)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Converts JSON arrays to typed slices", t, func() {
+		result, err := Get[[]string](config, "array")
+		So(result, ShouldResemble, []string{"woohoo", "true", "10", "3.5"})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Converts JSON objects to typed maps", t, func() {
+		result, err := Get[map[string]string](config, "object")
+		So(result, ShouldResemble, map[string]string{"string": "woohoo", "boolean": "true", "integer": "10", "float": "3.5"})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns an error naming the key when the conversion fails", t, func() {
+		result, err := Get[int](config, "float")
+		So(result, ShouldBeZeroValue)
+		So(err.Error(), ShouldEqual, "failed to read key 'float': cannot convert float64 value to int: value would lose precision")
+
+		conversionError := &ConversionError{}
+		So(errors.As(err, &conversionError), ShouldBeTrue)
+		So(conversionError.Type, ShouldEqual, reflect.TypeOf(0))
+	})
+
+	Convey("Returns an error when the key doesn't exist", t, func() {
+		result, err := Get[string](config, "missing")
+		So(result, ShouldBeZeroValue)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestCoerce(t *testing.T) {
+
+	Convey("Returns values that already have the requested type", t, func() {
+		result, err := coerce[map[string]interface{}](map[string]interface{}{"one": 1})
+		So(result, ShouldResemble, map[string]interface{}{"one": 1})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Converts values to interfaces they implement", t, func() {
+		result, err := coerce[interface{}](5)
+		So(result, ShouldEqual, 5)
+		So(err, ShouldBeNil)
+
+		stringer, err := coerce[interface{ String() string }](time.Second)
+		So(stringer.String(), ShouldEqual, "1s")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Converts nil", t, func() {
+
+		Convey("Into nil maps, slices and interfaces", func() {
+			slice, err := coerce[[]string](nil)
+			So(slice, ShouldBeNil)
+			So(err, ShouldBeNil)
+
+			value, err := coerce[interface{}](nil)
+			So(value, ShouldBeNil)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error for other types", func() {
+			_, err := coerce[string](nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Converts booleans", t, func() {
+
+		Convey("From booleans", func() {
+			result, err := coerce[bool](true)
+			So(result, ShouldBeTrue)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From strings", func() {
+			result, err := coerce[bool]("true")
+			So(result, ShouldBeTrue)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error for invalid strings", func() {
+			_, err := coerce[bool]("yes please")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Returns an error for numbers", func() {
+			_, err := coerce[bool](5)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Converts integers", t, func() {
+
+		Convey("From other integer types", func() {
+			result, err := coerce[int8](int64(100))
+			So(result, ShouldEqual, 100)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From floats without a fractional part", func() {
+			result, err := coerce[int](float64(1))
+			So(result, ShouldEqual, 1)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From strings", func() {
+			result, err := coerce[int]("42")
+			So(result, ShouldEqual, 42)
+			So(err, ShouldBeNil)
+
+			result, err = coerce[int]("42.0")
+			So(result, ShouldEqual, 42)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From JSON numbers", func() {
+			result, err := coerce[int64](json.Number("12"))
+			So(result, ShouldEqual, 12)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error when converting would truncate the value", func() {
+			_, err := coerce[int](1.5)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Returns an error when the value overflows", func() {
+			_, err := coerce[int8](300)
+			So(err.Error(), ShouldEqual, "cannot convert int value to int8: value overflows the target type")
+
+			_, err = coerce[int64](uint64(math.MaxUint64))
+			So(err, ShouldNotBeNil)
+
+			_, err = coerce[int64](1e19)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Returns an error for invalid strings and other types", func() {
+			_, err := coerce[int]("Hello")
+			So(err, ShouldNotBeNil)
+
+			_, err = coerce[int](true)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Converts unsigned integers", t, func() {
+
+		Convey("From signed integers, floats and strings", func() {
+			result, err := coerce[uint16](10)
+			So(result, ShouldEqual, 10)
+			So(err, ShouldBeNil)
+
+			result, err = coerce[uint16](10.0)
+			So(result, ShouldEqual, 10)
+			So(err, ShouldBeNil)
+
+			result, err = coerce[uint16]("10")
+			So(result, ShouldEqual, 10)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error for negative values", func() {
+			_, err := coerce[uint](-1)
+			So(err.Error(), ShouldEqual, "cannot convert int value to uint: value is negative")
+		})
+
+		Convey("Returns an error when the value overflows", func() {
+			_, err := coerce[uint8](256)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Converts floats", t, func() {
+
+		Convey("From floats", func() {
+			result, err := coerce[float64](3.3)
+			So(result, ShouldEqual, 3.3)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From integers", func() {
+			result, err := coerce[float64](10)
+			So(result, ShouldEqual, 10)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From strings", func() {
+			result, err := coerce[float64]("3.5")
+			So(result, ShouldEqual, 3.5)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error when an integer would lose precision", func() {
+			_, err := coerce[float64](int64(math.MaxInt64 - 1))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Returns an error when the value overflows", func() {
+			_, err := coerce[float32](math.MaxFloat64)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Returns an error for invalid strings", func() {
+			_, err := coerce[float64]("Hello")
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Converts strings", t, func() {
+
+		Convey("From strings", func() {
+			result, err := coerce[string]("one")
+			So(result, ShouldEqual, "one")
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From numbers and booleans", func() {
+			result, err := coerce[string](5)
+			So(result, ShouldEqual, "5")
+			So(err, ShouldBeNil)
+
+			result, err = coerce[string](uint(5))
+			So(result, ShouldEqual, "5")
+			So(err, ShouldBeNil)
+
+			result, err = coerce[string](1000000.5)
+			So(result, ShouldEqual, "1000000.5")
+			So(err, ShouldBeNil)
+
+			result, err = coerce[string](false)
+			So(result, ShouldEqual, "false")
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From stringers", func() {
+			result, err := coerce[string](3 * time.Second)
+			So(result, ShouldEqual, "3s")
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From byte slices", func() {
+			result, err := coerce[string]([]byte("bytes"))
+			So(result, ShouldEqual, "bytes")
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error for maps and slices", func() {
+			_, err := coerce[string](map[string]interface{}{})
+			So(err, ShouldNotBeNil)
+
+			_, err = coerce[string]([]interface{}{})
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Converts slices", t, func() {
+
+		Convey("From interface slices", func() {
+			result, err := coerce[[]int]([]interface{}{1, 2.0, "3"})
+			So(result, ShouldResemble, []int{1, 2, 3})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From other typed slices", func() {
+			result, err := coerce[[]int]([]float64{1, 2})
+			So(result, ShouldResemble, []int{1, 2})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From strings into byte slices", func() {
+			result, err := coerce[[]byte]("bytes")
+			So(result, ShouldResemble, []byte("bytes"))
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error naming the element that failed", func() {
+			_, err := coerce[[]int]([]float64{1.5, 2})
+			So(err.Error(), ShouldEqual, "element 0: cannot convert float64 value to int: value would lose precision")
+		})
+
+		Convey("Returns an error for other types", func() {
+			_, err := coerce[[]int](5)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Converts maps", t, func() {
+
+		Convey("From interface maps", func() {
+			result, err := coerce[map[string]int](map[string]interface{}{"one": 1.0, "two": "2"})
+			So(result, ShouldResemble, map[string]int{"one": 1, "two": 2})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Converts keys as well as values", func() {
+			result, err := coerce[map[int][]string](map[string]interface{}{"1": []interface{}{"a"}})
+			So(result, ShouldResemble, map[int][]string{1: {"a"}})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error naming the key that failed", func() {
+			_, err := coerce[map[string]int](map[string]interface{}{"one": "uno"})
+			So(err.Error(), ShouldEqual, "value of key 'one': cannot convert string value to int: not a number")
+
+			_, err = coerce[map[int]int](map[string]interface{}{"one": 1})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("Returns an error for other types", func() {
+			_, err := coerce[map[string]interface{}](5)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Returns an error for unsupported types", t, func() {
+		_, err := coerce[struct{}](5)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -1,150 +1,487 @@
 package internal
 
-import "github.com/mitchellh/mapstructure"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
 
 // Loader defines a generic loader interface
 type Loader interface {
 	Load() (map[string]interface{}, error)
 }
 
-// Config defines the overall configuration structure
+// Config defines the overall configuration structure. It's safe for concurrent use: readers work on immutable
+// snapshots of the loaded configuration, and writers swap in a new snapshot atomically
 type Config struct {
-	Map map[string]interface{}
+	options       options
+	state         atomic.Value
+	mutex         sync.Mutex
+	position      int
+	subscriptions []*subscription
+	sub           bool
+}
+
+// errSubConfigReload is returned when reloading a sub config, which only holds a copy of part of a configuration
+var errSubConfigReload = errors.New("a sub config can't be reloaded, reload the configuration it came from instead")
+
+// state defines a snapshot of the loaded configuration. A state is never modified once it's stored in a Config, apart
+// from caching the secrets that are read from it
+type state struct {
+	values        map[string]interface{}
+	raw           map[string]interface{}
+	interpolation InterpolationErrors
+	secrets       *secretCache
+	layers        []*layer
+	setLayer      *layer
+	sets          []setOperation
+}
+
+// setOperation defines a value written with Set, which is written again whenever the configuration is reloaded
+type setOperation struct {
+	keys  []string
+	value interface{}
 }
 
 // NewConfig creates a new configuration structure
-func NewConfig() *Config {
-	return &Config{
-		Map: map[string]interface{}{},
+func NewConfig(configOptions ...Option) *Config {
+	appliedOptions := defaultOptions()
+	for _, option := range configOptions {
+		option(&appliedOptions)
+	}
+
+	return newConfig(&state{values: map[string]interface{}{}, secrets: newSecretCache()}, appliedOptions)
+}
+
+// newConfig creates a new configuration structure from the supplied state and options
+func newConfig(initialState *state, configOptions options) *Config {
+	config := &Config{
+		options: configOptions,
 	}
+	config.state.Store(initialState)
+	return config
 }
 
-// Use adds a loader to the configuration loading chain
+// splitKey splits the supplied key into path segments using the configured delimiter
+func (config *Config) splitKey(key string) []string {
+	delimiter := config.options.delimiter
+	if len(delimiter) == 0 {
+		delimiter = defaultDelimiter
+	}
+	return splitKey(key, delimiter)
+}
+
+// current returns the current state of the configuration
+func (config *Config) current() *state {
+	currentState, _ := config.state.Load().(*state)
+	if currentState == nil {
+		return &state{values: map[string]interface{}{}}
+	}
+	return currentState
+}
+
+// rawValues returns the values in the state before interpolation
+func (currentState *state) rawValues() map[string]interface{} {
+	if currentState.raw == nil {
+		return currentState.values
+	}
+	return currentState.raw
+}
+
+// resolve sets the values of a new state from its raw values, interpolating them if interpolation is enabled. Secrets
+// are resolved again for every new state
+func (config *Config) resolve(newState *state) *state {
+	newState.values = newState.raw
+	newState.secrets = newSecretCache()
+	if config.options.interpolate {
+		newState.values, newState.interpolation = interpolate(newState.raw, config.options)
+	}
+	return newState
+}
+
+// Map returns a copy of the loaded configuration map. Secret references are left as they are
+func (config *Config) Map() map[string]interface{} {
+	return copyMap(config.current().values)
+}
+
+// Snapshot returns a configuration containing the currently loaded values, which won't change when the original
+// configuration is reloaded or modified. Use it to read several values that should be consistent with each other
+func (config *Config) Snapshot() *Config {
+	return newConfig(config.current(), config.options)
+}
+
+// Use adds a loader to the configuration loading chain, panicking if the loader fails. References that can't be
+// interpolated don't fail the loader, since a later loader may supply them, so call Validate once every loader has
+// been added to check them
 func (config *Config) Use(loader Loader) {
+	err := config.TryUse(loader)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// TryUse adds a loader to the configuration loading chain, returning a *LoaderError if the loader fails. References
+// that can't be interpolated aren't returned, since a later loader may supply them, so call Validate once every loader
+// has been added to check them
+func (config *Config) TryUse(loader Loader) error {
+	err := config.use(loader)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// use adds a loader to the configuration loading chain, returning the loader's failure if it fails
+func (config *Config) use(loader Loader) *LoaderError {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	index := config.position
+	config.position++
 
 	// Load in the config map from this loader
-	loadedMap, err := loader.Load()
+	loadedMap, names, err := loadWithNames(loader)
 	if err != nil {
-		panic(err)
+		return newLoaderError(index, loader, err)
 	}
 
-	// Merge it with our existing values
-	merge(config.Map, loadedMap)
+	// Keep the loaded map around for provenance, and merge a copy of it with our existing values
+	currentState := config.current()
+	loadedLayer := newLayer(index, loader, loadedMap, names)
+	config.state.Store(config.resolve(&state{
+		raw:      mergeMaps(copyMap(currentState.rawValues()), copyMap(loadedLayer.values), config.options.caseInsensitive),
+		layers:   append(currentState.layers[:len(currentState.layers):len(currentState.layers)], loadedLayer),
+		setLayer: currentState.setLayer,
+		sets:     currentState.sets,
+	}))
+	return nil
 }
 
-// ToStructure maps the loaded configuration to a structure
-func (config *Config) ToStructure(structure interface{}) error {
-	return mapstructure.Decode(config.Map, structure)
+// Reload re-runs every loader in the chain in its original order and replaces the loaded configuration with the result,
+// writing the values from Set back over it. Values that can no longer be set, such as an index past the end of a slice
+// that shrank, are discarded. If any loader fails, the existing configuration is kept and a LoaderErrors is returned.
+// The same happens with an InterpolationErrors if references in the result can't be resolved, and with a SchemaErrors
+// if the configuration has a schema that the result doesn't match. Sub configs can't be reloaded
+func (config *Config) Reload() error {
+	if config.sub {
+		return errSubConfigReload
+	}
+
+	config.mutex.Lock()
+	currentState := config.current()
+
+	var errors LoaderErrors
+	layers := make([]*layer, 0, len(currentState.layers))
+
+	for _, configLayer := range currentState.layers {
+		if configLayer.loader == nil {
+			continue
+		}
+
+		loadedMap, names, err := loadWithNames(configLayer.loader)
+		if err != nil {
+			errors = append(errors, newLoaderError(configLayer.source.Index, configLayer.loader, err))
+			continue
+		}
+		layers = append(layers, newLayer(configLayer.source.Index, configLayer.loader, loadedMap, names))
+	}
+
+	if len(errors) > 0 {
+		config.mutex.Unlock()
+		return errors
+	}
+
+	// Merge the new layers in priority order, and write the values from Set back over them
+	merged := map[string]interface{}{}
+	for _, loadedLayer := range layers {
+		mergeMaps(merged, copyMap(loadedLayer.values), config.options.caseInsensitive)
+	}
+	sets, setLayer := replaySets(merged, currentState.sets, config.options.caseInsensitive)
+
+	reloadedState := config.resolve(&state{
+		raw:      merged,
+		layers:   layers,
+		setLayer: setLayer,
+		sets:     sets,
+	})
+
+	// Keep the existing configuration if any of its references can't be resolved
+	if len(reloadedState.interpolation) > 0 {
+		config.mutex.Unlock()
+		return reloadedState.interpolation
+	}
+
+	// Keep the existing configuration if the new one doesn't match the schema
+	if config.options.schema != nil {
+		err := validateSchema(config.options.schema, reloadedState, config.options)
+		if err != nil {
+			config.mutex.Unlock()
+			return err
+		}
+	}
+
+	config.state.Store(reloadedState)
+	subscriptions := config.subscriptions
+	config.mutex.Unlock()
+
+	notify(subscriptions, currentState.values, reloadedState.values)
+	return nil
+}
+
+// Chain starts a chain of loaders that collects every loader failure instead of stopping at the first one
+func (config *Config) Chain() *Chain {
+	return &Chain{
+		config: config,
+	}
+}
+
+// ToStructure maps the loaded configuration to a structure. Fields with a `default` tag take the default when no
+// loader supplied their key, and fields are then checked against the rules in their `validate` tags
+func (config *Config) ToStructure(structure interface{}, decodeOptions ...DecodeOption) error {
+	return config.toStructure([]string{}, structure, applyDecodeOptions(decodeOptions))
+}
+
+// ToStructureAt maps the value at the supplied key to a structure, the same way as ToStructure. Errors include the
+// full path of each key
+func (config *Config) ToStructureAt(key string, structure interface{}, decodeOptions ...DecodeOption) error {
+	return config.toStructure(config.splitKey(key), structure, applyDecodeOptions(decodeOptions))
+}
+
+// toStructure maps the value at the supplied keys to a structure
+func (config *Config) toStructure(keys []string, structure interface{}, appliedOptions decodeOptions) error {
+	currentState := config.current()
+	value, err := getPath(currentState.values, keys, config.options.caseInsensitive)
+	if err != nil {
+		return fmt.Errorf("failed to get key '%s': %w", joinKey(keys, config.options.delimiter), err)
+	}
+
+	defaults, err := structureDefaults(reflect.TypeOf(structure), keys)
+	if err != nil {
+		return err
+	}
+
+	value, err = config.resolveSecrets(currentState, keys, copyValue(value))
+	if err != nil {
+		return err
+	}
+
+	// mapstructure matches keys to fields regardless of case, so defaults have to be merged the same way
+	mapValue, isMap := value.(map[string]interface{})
+	if isMap {
+		value = mergeMaps(mapValue, defaults, true)
+	}
+
+	metadata := &mapstructure.Metadata{}
+	err = decodeMetadata(value, structure, metadata, config.options.decodeHooks)
+	if err != nil {
+		return decodeError(err, keys, config.options.delimiter)
+	}
+
+	report := newDecodeReport(metadata, keys, currentState, config.options)
+	if appliedOptions.report != nil {
+		*appliedOptions.report = *report
+	}
+	if appliedOptions.strict && len(report.Unused) > 0 {
+		return &UnusedKeysError{Keys: report.Unused}
+	}
+
+	return validate(currentState, keys, config.options.delimiter, structure)
 }
 
-// Get gets a key from the loaded configuration
+// Get gets a key from the loaded configuration, resolving any secret references in it. Maps and slices are copied, so
+// they can be modified freely
 func (config *Config) Get(key string) (interface{}, error) {
-	return get(config.Map, splitKey(key))
+	currentState := config.current()
+	keys := config.splitKey(key)
+	value, err := getPath(currentState.values, keys, config.options.caseInsensitive)
+	if err != nil {
+		return nil, err
+	}
+	return config.resolveSecrets(currentState, keys, copyValue(value))
 }
 
-// GetSubConfig gets a loaded submap as a configuration structure
+// GetSubConfig gets a loaded submap as a configuration structure. The sub config is a copy that can't be reloaded or
+// watched, so get it again after reloading the configuration it came from
 func (config *Config) GetSubConfig(key string) (*Config, error) {
 	value, err := config.GetMap(key)
 	if err != nil {
 		return nil, err
 	}
-	return &Config{
-		Map: value,
-	}, nil
+
+	// Narrow every layer down to the sub-map so the sub config can still report sources
+	keys := config.splitKey(key)
+	currentState := config.current()
+	subState := &state{
+		values: value,
+	}
+	if currentState.setLayer != nil {
+		subState.setLayer = currentState.setLayer.sub(keys, config.options.caseInsensitive)
+	}
+	for _, configLayer := range currentState.layers {
+		subLayer := configLayer.sub(keys, config.options.caseInsensitive)
+		if subLayer != nil {
+			subState.layers = append(subState.layers, subLayer)
+		}
+	}
+
+	// The values have already been interpolated and their secrets resolved, so doing it again would undo escaped
+	// references
+	subOptions := config.options
+	subOptions.interpolate = false
+	subOptions.secretResolvers = nil
+	subConfig := newConfig(subState, subOptions)
+	subConfig.sub = true
+	return subConfig, nil
 }
 
 // GetMap gets a map from the loaded configuration
 func (config *Config) GetMap(key string) (map[string]interface{}, error) {
-	value, err := config.Get(key)
-	if err != nil {
-		return nil, err
-	}
-	return cast[map[string]interface{}](value)
+	return Get[map[string]interface{}](config, key)
 }
 
 // GetSlice gets a slice from the loaded configuration
 func (config *Config) GetSlice(key string) ([]interface{}, error) {
-	value, err := config.Get(key)
-	if err != nil {
-		return nil, err
-	}
-	return cast[[]interface{}](value)
+	return Get[[]interface{}](config, key)
 }
 
 // GetStringSlice gets a string slice from the loaded configuration
 func (config *Config) GetStringSlice(key string) ([]string, error) {
-	value, err := config.Get(key)
-	if err != nil {
-		return nil, err
-	}
-	return cast[[]string](value)
+	return Get[[]string](config, key)
 }
 
 // GetString gets a string from the loaded configuration
 func (config *Config) GetString(key string) (string, error) {
-	value, err := config.Get(key)
-	if err != nil {
-		return "", err
-	}
-	return cast[string](value)
+	return Get[string](config, key)
 }
 
 // GetIntegerSlice gets a integer slice from the loaded configuration
 func (config *Config) GetIntegerSlice(key string) ([]int, error) {
-	value, err := config.Get(key)
-	if err != nil {
-		return nil, err
-	}
-	return castIntegerSlice(value)
+	return Get[[]int](config, key)
 }
 
 // GetInteger gets a integer from the loaded configuration
 func (config *Config) GetInteger(key string) (int, error) {
-	value, err := config.Get(key)
-	if err != nil {
-		return 0, err
-	}
-	return castInteger(value)
+	return Get[int](config, key)
 }
 
 // GetBooleanSlice gets a boolean slice from the loaded configuration
 func (config *Config) GetBooleanSlice(key string) ([]bool, error) {
-	value, err := config.Get(key)
-	if err != nil {
-		return nil, err
-	}
-	return cast[[]bool](value)
+	return Get[[]bool](config, key)
 }
 
 // GetBoolean gets a boolean from the loaded configuration
 func (config *Config) GetBoolean(key string) (bool, error) {
-	value, err := config.Get(key)
-	if err != nil {
-		return false, err
-	}
-	return cast[bool](value)
+	return Get[bool](config, key)
 }
 
 // GetFloatSlice gets a float slice from the loaded configuration
 func (config *Config) GetFloatSlice(key string) ([]float64, error) {
-	value, err := config.Get(key)
-	if err != nil {
-		return nil, err
-	}
-	return cast[[]float64](value)
+	return Get[[]float64](config, key)
 }
 
 // GetFloat gets a float from the loaded configuration
 func (config *Config) GetFloat(key string) (float64, error) {
-	value, err := config.Get(key)
-	if err != nil {
-		return 0, err
-	}
-	return cast[float64](value)
+	return Get[float64](config, key)
+}
+
+// GetDurationSlice gets a duration slice from the loaded configuration
+func (config *Config) GetDurationSlice(key string) ([]time.Duration, error) {
+	return Get[[]time.Duration](config, key)
+}
+
+// GetDuration gets a duration from the loaded configuration
+func (config *Config) GetDuration(key string) (time.Duration, error) {
+	return Get[time.Duration](config, key)
+}
+
+// GetTimeSlice gets a time slice from the loaded configuration
+func (config *Config) GetTimeSlice(key string) ([]time.Time, error) {
+	return Get[[]time.Time](config, key)
+}
+
+// GetTime gets a time from the loaded configuration
+func (config *Config) GetTime(key string) (time.Time, error) {
+	return Get[time.Time](config, key)
+}
+
+// GetByteSizeSlice gets a byte size slice from the loaded configuration
+func (config *Config) GetByteSizeSlice(key string) ([]ByteSize, error) {
+	return Get[[]ByteSize](config, key)
+}
+
+// GetByteSize gets a byte size from the loaded configuration
+func (config *Config) GetByteSize(key string) (ByteSize, error) {
+	return Get[ByteSize](config, key)
 }
 
 // Set sets a value in the loaded configuration
 func (config *Config) Set(key string, value interface{}) error {
-	_, err := set(config.Map, splitKey(key), value)
-	return err
+	config.mutex.Lock()
+	currentState := config.current()
+
+	// Copy the value so later changes to it by the caller don't leak into the configuration
+	keys := config.splitKey(key)
+	value = copyValue(value)
+	raw, err := setPath(copyMap(currentState.rawValues()), keys, value, setOptions{
+		fold:      config.options.caseInsensitive,
+		delimiter: config.options.delimiter,
+	})
+	if err != nil {
+		config.mutex.Unlock()
+		return err
+	}
+
+	// Record the value in the set layer so its source can be reported
+	setLayer := &layer{source: setSource, values: map[string]interface{}{}}
+	if currentState.setLayer != nil {
+		setLayer.values = copyMap(currentState.setLayer.values)
+	}
+	_, _ = setPath(setLayer.values, keys, copyValue(value), setOptions{fold: config.options.caseInsensitive})
+
+	updatedState := config.resolve(&state{
+		raw:      raw,
+		layers:   currentState.layers,
+		setLayer: setLayer,
+		sets:     append(currentState.sets[:len(currentState.sets):len(currentState.sets)], setOperation{keys: keys, value: copyValue(value)}),
+	})
+	config.state.Store(updatedState)
+	subscriptions := config.subscriptions
+	config.mutex.Unlock()
+
+	notify(subscriptions, currentState.values, updatedState.values)
+	return nil
+}
+
+// replaySets writes the values from Set back over a reloaded map, returning the sets that still apply and a set layer
+// containing their values. Sets that no longer apply, such as an index past the end of a slice that shrank, are
+// discarded so they aren't reported as sources or written again on later reloads
+func replaySets(merged map[string]interface{}, operations []setOperation, fold bool) ([]setOperation, *layer) {
+	var sets []setOperation
+	var setLayer *layer
+	for _, operation := range operations {
+		_, err := setPath(merged, operation.keys, copyValue(operation.value), setOptions{replace: true, fold: fold})
+		if err != nil {
+			continue
+		}
+
+		if setLayer == nil {
+			setLayer = &layer{source: setSource, values: map[string]interface{}{}}
+		}
+		_, _ = setPath(setLayer.values, operation.keys, copyValue(operation.value), setOptions{replace: true, fold: fold})
+		sets = append(sets, operation)
+	}
+	return sets, setLayer
+}
+
+// allLayers returns every layer in the state in priority order, including values written with Set
+func (currentState *state) allLayers() []*layer {
+	if currentState.setLayer == nil {
+		return currentState.layers
+	}
+	return append([]*layer{currentState.setLayer}, currentState.layers...)
 }
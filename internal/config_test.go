@@ -1,7 +1,13 @@
 package internal
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -11,14 +17,14 @@ func TestUse(t *testing.T) {
 	Convey("Adds a loaded config to the config map", t, func() {
 		config := NewConfig()
 		config.Use(NewMapLoader(map[string]interface{}{"one": 1}))
-		So(config.Map, ShouldResemble, map[string]interface{}{"one": 1})
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 1})
 	})
 
 	Convey("Merges the new config with previous configs", t, func() {
 		config := NewConfig()
 		config.Use(NewMapLoader(map[string]interface{}{"one": 1}))
 		config.Use(NewMapLoader(map[string]interface{}{"two": 2}))
-		So(config.Map, ShouldResemble, map[string]interface{}{"one": 1, "two": 2})
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 1, "two": 2})
 	})
 
 	Convey("Panics if the config failed to load", t, func() {
@@ -27,6 +33,29 @@ func TestUse(t *testing.T) {
 	})
 }
 
+func TestTryUse(t *testing.T) {
+
+	Convey("Adds a loaded config to the config map", t, func() {
+		config := NewConfig()
+		err := config.TryUse(NewMapLoader(map[string]interface{}{"one": 1}))
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 1})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns a loader error if the config failed to load", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"one": 1}))
+		err := config.TryUse(NewJSONFileLoader("missing.json", false))
+
+		So(err, ShouldHaveSameTypeAs, &LoaderError{})
+		loaderError := err.(*LoaderError)
+		So(loaderError.Kind, ShouldEqual, "JSON file")
+		So(loaderError.Name, ShouldEqual, "missing.json")
+		So(loaderError.Index, ShouldEqual, 1)
+		So(errors.Is(err, os.ErrNotExist), ShouldBeTrue)
+	})
+}
+
 func TestToStructure(t *testing.T) {
 
 	Convey("Converts the config map to a structure", t, func() {
@@ -71,11 +100,107 @@ func TestGetters(t *testing.T) {
 		})
 	})
 
+	Convey("Typed getters", t, func() {
+		config := NewConfig()
+		config.Use(NewJSONFileLoader("../test/test.json", false))
+
+		Convey("Convert JSON numbers to integers", func() {
+			value, err := config.GetInteger("object:integer")
+			So(value, ShouldEqual, 10)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Convert JSON arrays to typed slices", func() {
+			config.Use(NewMapLoader(map[string]interface{}{"strings": []interface{}{"a", "b"}}))
+			value, err := config.GetStringSlice("strings")
+			So(value, ShouldResemble, []string{"a", "b"})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Read durations, times and byte sizes", func() {
+			config.Use(NewMapLoader(map[string]interface{}{
+				"timeout": "30s",
+				"started": 1704164645,
+				"limit":   "10MB",
+				"retries": []interface{}{"1s", 2.5},
+			}))
+
+			timeout, err := config.GetDuration("timeout")
+			So(timeout, ShouldEqual, 30*time.Second)
+			So(err, ShouldBeNil)
+
+			started, err := config.GetTime("started")
+			So(started.Unix(), ShouldEqual, 1704164645)
+			So(err, ShouldBeNil)
+
+			limit, err := config.GetByteSize("limit")
+			So(limit, ShouldEqual, 10*Megabyte)
+			So(err, ShouldBeNil)
+
+			retries, err := config.GetDurationSlice("retries")
+			So(retries, ShouldResemble, []time.Duration{time.Second, 2500 * time.Millisecond})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Return an error when a conversion would lose data", func() {
+			value, err := config.GetInteger("float")
+			So(value, ShouldBeZeroValue)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Slice indexing", t, func() {
+		config := NewConfig()
+		config.Use(NewYAMLFileLoader("../test/test.yaml", false))
+		config.Use(NewMapLoader(map[string]interface{}{
+			"servers": []interface{}{map[string]interface{}{"host": "a", "port": 80}},
+		}))
+
+		Convey("Gets values inside slices", func() {
+			value, err := config.GetString("servers:0:host")
+			So(value, ShouldEqual, "a")
+			So(err, ShouldBeNil)
+
+			boolean, err := config.GetBoolean("array:1")
+			So(boolean, ShouldBeTrue)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Gets sub configs from slice elements", func() {
+			subConfig, err := config.GetSubConfig("servers:0")
+			So(err, ShouldBeNil)
+			So(subConfig.Map(), ShouldResemble, map[string]interface{}{"host": "a", "port": 80})
+
+			source, err := subConfig.Source("port")
+			So(source, ShouldResemble, Source{Kind: "map", Index: 1})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Sets values in slices, growing them and keeping them through a reload", func() {
+			So(config.Set("servers:1:host", "b"), ShouldBeNil)
+			So(config.Set("servers:0:weight", 2), ShouldBeNil)
+			So(config.Reload(), ShouldBeNil)
+
+			value, err := config.GetSlice("servers")
+			So(value, ShouldResemble, []interface{}{
+				map[string]interface{}{"host": "a", "port": 80, "weight": 2},
+				map[string]interface{}{"host": "b"},
+			})
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error when an index is out of range", func() {
+			_, err := config.Get("servers:3:host")
+			So(err.Error(), ShouldEqual, "index 3 is out of range for key 'servers' with 1 elements")
+			So(config.Set("servers:3:host", "d"), ShouldNotBeNil)
+		})
+	})
+
 	Convey("GetSubConfig", t, func() {
 
 		Convey("Constructs a new config object containing the sub-map", func() {
 			value, err := config.GetSubConfig("Map")
-			So(value.Map, ShouldResemble, map[string]interface{}{"Two": "Hi"})
+			So(value.Map(), ShouldResemble, map[string]interface{}{"Two": "Hi"})
 			So(err, ShouldBeNil)
 		})
 
@@ -86,3 +211,185 @@ func TestGetters(t *testing.T) {
 		})
 	})
 }
+
+func TestMap(t *testing.T) {
+
+	Convey("Returns a copy of the loaded configuration", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"map": map[string]interface{}{"one": 1}}))
+
+		m := config.Map()
+		m["map"].(map[string]interface{})["one"] = 2
+		So(config.Map(), ShouldResemble, map[string]interface{}{"map": map[string]interface{}{"one": 1}})
+	})
+
+	Convey("Keeps a copy of the values passed to Set", t, func() {
+		config := NewConfig()
+		hosts := []string{"a", "b"}
+		So(config.Set("hosts", hosts), ShouldBeNil)
+
+		hosts[0] = "changed"
+		So(config.MustGetStringSlice("hosts"), ShouldResemble, []string{"a", "b"})
+		So(config.Map(), ShouldResemble, map[string]interface{}{"hosts": []string{"a", "b"}})
+
+		config.Map()["hosts"].([]string)[1] = "changed"
+		So(config.MustGetStringSlice("hosts"), ShouldResemble, []string{"a", "b"})
+	})
+
+	Convey("Returns an empty map for a zero value configuration", t, func() {
+		So((&Config{}).Map(), ShouldBeEmpty)
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+
+	Convey("Keeps the values loaded when the snapshot was taken", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"one": 1}))
+
+		snapshot := config.Snapshot()
+		So(config.Set("two", 2), ShouldBeNil)
+
+		So(snapshot.Map(), ShouldResemble, map[string]interface{}{"one": 1})
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 1, "two": 2})
+	})
+
+	Convey("Reports sources from the snapshot", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"one": 1}))
+
+		source, err := config.Snapshot().Source("one")
+		So(source, ShouldResemble, Source{Kind: "map", Index: 0})
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestConcurrentUse(t *testing.T) {
+
+	Convey("Allows reads while the configuration is being written", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"map": map[string]interface{}{"one": 1}}))
+
+		var changes int64
+		config.OnChange("*", func(oldValue interface{}, newValue interface{}) {
+			atomic.AddInt64(&changes, 1)
+		})
+
+		waitGroup := sync.WaitGroup{}
+		for i := 0; i < 4; i++ {
+			waitGroup.Add(2)
+
+			go func(i int) {
+				defer waitGroup.Done()
+				for j := 0; j < 50; j++ {
+					_ = config.Set(fmt.Sprintf("map:%d-%d", i, j), j)
+					_ = config.Reload()
+				}
+			}(i)
+
+			go func() {
+				defer waitGroup.Done()
+				for j := 0; j < 50; j++ {
+					_, _ = config.GetInteger("map:one")
+					_, _ = config.Source("map:one")
+					_, _ = config.Explain("map")
+					_ = config.Snapshot().Map()
+				}
+			}()
+		}
+		waitGroup.Wait()
+
+		value, err := config.GetMap("map")
+		So(value, ShouldHaveLength, 201)
+		So(err, ShouldBeNil)
+		So(atomic.LoadInt64(&changes), ShouldEqual, 200)
+	})
+}
+
+func TestToStructureAt(t *testing.T) {
+	load := func() *Config {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{
+			"db": map[string]interface{}{
+				"primary": map[string]interface{}{"host": "localhost", "port": 5432},
+				"replicas": []interface{}{
+					map[string]interface{}{"host": "replica", "port": "many"},
+				},
+			},
+			"timeout": "30s",
+		}))
+		return config
+	}
+
+	type server struct {
+		Host    string        `mapstructure:"host" validate:"required"`
+		Port    int           `mapstructure:"port" validate:"min=1"`
+		Timeout time.Duration `mapstructure:"timeout" default:"5s"`
+	}
+
+	Convey("Copies the value at a key to a structure", t, func() {
+		structure := server{}
+		So(load().ToStructureAt("db:primary", &structure), ShouldBeNil)
+		So(structure, ShouldResemble, server{Host: "localhost", Port: 5432, Timeout: 5 * time.Second})
+	})
+
+	Convey("Copies values that aren't maps", t, func() {
+		var timeout time.Duration
+		So(load().ToStructureAt("timeout", &timeout), ShouldBeNil)
+		So(timeout, ShouldEqual, 30*time.Second)
+
+		var replicas []map[string]interface{}
+		So(load().ToStructureAt("db:replicas", &replicas), ShouldBeNil)
+		So(replicas, ShouldHaveLength, 1)
+	})
+
+	Convey("Returns decoding errors with the full key", t, func() {
+		err := load().ToStructureAt("db:replicas:0", &server{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "'db:replicas:0:port'")
+
+		err = load().ToStructureAt("db", &struct {
+			Replicas []server `mapstructure:"replicas"`
+		}{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "'db:replicas:0:port'")
+
+		var port int
+		err = load().ToStructureAt("db:primary:host", &port)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "'db:primary:host'")
+	})
+
+	Convey("Returns an error with the full key when the key doesn't exist", t, func() {
+		err := load().ToStructureAt("db:secondary", &server{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "failed to get key 'db:secondary': key 'secondary' was not found")
+
+		err = load().ToStructureAt("db:replicas:1", &server{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "failed to get key 'db:replicas:1': index 1 is out of range for key 'replicas' with 1 elements")
+	})
+
+	Convey("Uses the same decode options as ToStructure", t, func() {
+		config := load()
+		So(config.Set("db:primary:user", "admin"), ShouldBeNil)
+
+		err := config.ToStructureAt("db:primary", &server{}, Strict())
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "configuration contains keys that don't map to any field: 'db:primary:user' from set (position -1)")
+
+		report := DecodeReport{}
+		So(config.ToStructureAt("db:primary", &server{}, WithReport(&report)), ShouldBeNil)
+		So(report.Unset, ShouldResemble, []string{"db:primary:timeout"})
+	})
+
+	Convey("Returns validation errors with the full key and source", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"db": map[string]interface{}{"primary": map[string]interface{}{"host": "a"}}}))
+		So(config.Set("db:primary:port", 0), ShouldBeNil)
+
+		err := config.ToStructureAt("db:primary", &server{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "key 'db:primary:port' from set (position -1) must be at least 1, got 0")
+	})
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// DecodeOption defines an option that changes how a configuration is copied to a structure
+type DecodeOption func(*decodeOptions)
+
+// decodeOptions defines the configurable behaviour of copying a configuration to a structure
+type decodeOptions struct {
+	strict bool
+	report *DecodeReport
+}
+
+// Strict makes copying to a structure fail with an *UnusedKeysError if the configuration contains keys that don't map
+// to any field
+func Strict() DecodeOption {
+	return func(options *decodeOptions) {
+		options.strict = true
+	}
+}
+
+// WithReport fills the supplied report with the keys that didn't map to any field and the fields that no loader set
+func WithReport(report *DecodeReport) DecodeOption {
+	return func(options *decodeOptions) {
+		options.report = report
+	}
+}
+
+// decode maps the supplied configuration map to a structure
+func decode(m map[string]interface{}, structure interface{}) error {
+	return decodeMetadata(m, structure, nil, nil)
+}
+
+// decodeMetadata maps the supplied configuration map to a structure using the supplied custom hooks, recording the
+// keys that were and weren't used in the metadata
+func decodeMetadata(value interface{}, structure interface{}, metadata *mapstructure.Metadata, hooks []DecodeHook) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: decodeHook(hooks),
+		Metadata:   metadata,
+		Result:     structure,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(value)
+}
+
+// decodeError rewrites the field names in mapstructure's errors (e.g. `servers[0].port`) as the full keys of the
+// values that were decoded, using the supplied delimiter
+func decodeError(err error, keys []string, delimiter string) error {
+	var decodeErrors *mapstructure.Error
+	if !errors.As(err, &decodeErrors) {
+		return errors.New(decodeErrorMessage(err.Error(), keys, delimiter))
+	}
+
+	rewritten := make([]string, len(decodeErrors.Errors))
+	for i, message := range decodeErrors.Errors {
+		rewritten[i] = decodeErrorMessage(message, keys, delimiter)
+	}
+	return &mapstructure.Error{Errors: rewritten}
+}
+
+// decodeErrorMessage rewrites the field name in a single mapstructure error message, which is the first quoted string
+func decodeErrorMessage(message string, keys []string, delimiter string) string {
+	start := strings.IndexByte(message, '\'')
+	if start < 0 {
+		return message
+	}
+	end := strings.IndexByte(message[start+1:], '\'')
+	if end < 0 {
+		return message
+	}
+
+	name := message[start+1 : start+1+end]
+	return message[:start+1] + joinKey(fullKeys(keys, name), delimiter) + message[start+1+end:]
+}
+
+// coerceHook is a decode hook that converts values into the types with their own conversion rules (durations, times
+// and byte sizes) using the same rules as the getters
+func coerceHook(_ reflect.Type, target reflect.Type, data interface{}) (interface{}, error) {
+	switch target {
+	case durationType, timeType, byteSizeType:
+		converted, err := coerceValue(data, target)
+		if err != nil {
+			return nil, err
+		}
+		return converted.Interface(), nil
+	default:
+		return data, nil
+	}
+}
@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDecode(t *testing.T) {
+
+	Convey("Decodes durations, times and byte sizes", t, func() {
+		structure := struct {
+			Timeout  time.Duration
+			Interval time.Duration
+			Started  time.Time
+			Limit    ByteSize
+			Limits   []ByteSize
+		}{}
+
+		err := decode(map[string]interface{}{
+			"Timeout":  "30s",
+			"Interval": 5,
+			"Started":  "2024-01-02T03:04:05Z",
+			"Limit":    "512MiB",
+			"Limits":   []interface{}{"1KB", 10},
+		}, &structure)
+
+		So(err, ShouldBeNil)
+		So(structure.Timeout, ShouldEqual, 30*time.Second)
+		So(structure.Interval, ShouldEqual, 5*time.Second)
+		So(structure.Started.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)), ShouldBeTrue)
+		So(structure.Limit, ShouldEqual, 512*Mebibyte)
+		So(structure.Limits, ShouldResemble, []ByteSize{Kilobyte, 10})
+	})
+
+	Convey("Returns an error when a value can't be converted", t, func() {
+		structure := struct{ Timeout time.Duration }{}
+		err := decode(map[string]interface{}{"Timeout": "soon"}, &structure)
+		So(err, ShouldNotBeNil)
+	})
+}
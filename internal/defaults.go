@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DefaultsLoader defines a loader that loads the defaults declared in the `default` tags of a structure's fields
+type DefaultsLoader struct {
+	structure reflect.Type
+}
+
+// NewDefaultsLoader creates a new defaults loader for the type of the supplied structure (or pointer to a structure)
+func NewDefaultsLoader(structure interface{}) *DefaultsLoader {
+	return &DefaultsLoader{
+		structure: reflect.TypeOf(structure),
+	}
+}
+
+// Load builds a configuration map from the default tags of the structure
+func (loader *DefaultsLoader) Load() (map[string]interface{}, error) {
+	structureType := loader.structure
+	for structureType != nil && structureType.Kind() == reflect.Pointer {
+		structureType = structureType.Elem()
+	}
+
+	if structureType == nil || structureType.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("defaults can only be loaded from a structure, got %v", loader.structure)
+	}
+
+	return structureDefaults(structureType, []string{})
+}
+
+// structureDefaults builds a map of the defaults declared on the fields of the supplied type, keyed the same way
+// mapstructure keys the fields. Types that aren't structures have no defaults
+func structureDefaults(structureType reflect.Type, keys []string) (map[string]interface{}, error) {
+	for structureType != nil && structureType.Kind() == reflect.Pointer {
+		structureType = structureType.Elem()
+	}
+
+	defaults := map[string]interface{}{}
+	if structureType == nil || structureType.Kind() != reflect.Struct || structureType == timeType {
+		return defaults, nil
+	}
+
+	for i := 0; i < structureType.NumField(); i++ {
+		field := structureType.Field(i)
+		name, squash, skip := fieldKey(field)
+
+		// Unexported fields can't be set, unless they're embedded structures squashed into their parent
+		if skip || (!field.IsExported() && !squash) {
+			continue
+		}
+		fieldKeys := append(keys[:len(keys):len(keys)], name)
+
+		// Fields with a default tag take the default, other fields may be structures with defaults of their own
+		defaultTag, hasDefault := field.Tag.Lookup("default")
+		if hasDefault {
+			value, err := defaultValue(defaultTag, field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("invalid default for key '%s': %w", joinKey(fieldKeys, defaultDelimiter), err)
+			}
+			defaults[name] = value
+			continue
+		}
+
+		if squash {
+			fieldKeys = keys
+		}
+		nested, err := structureDefaults(field.Type, fieldKeys)
+		if err != nil {
+			return nil, err
+		}
+		if len(nested) == 0 {
+			continue
+		}
+
+		if squash {
+			merge(defaults, nested)
+		} else {
+			defaults[name] = nested
+		}
+	}
+
+	return defaults, nil
+}
+
+// fieldKey returns the key mapstructure uses for the supplied field, whether the field is squashed into its parent,
+// and whether it should be skipped
+func fieldKey(field reflect.StructField) (string, bool, bool) {
+	tagParts := strings.Split(field.Tag.Get("mapstructure"), ",")
+
+	name := tagParts[0]
+	if name == "-" {
+		return "", false, true
+	}
+	if len(name) == 0 {
+		name = field.Name
+	}
+
+	squash := false
+	for _, option := range tagParts[1:] {
+		switch option {
+		case "squash":
+			squash = true
+		case "remain":
+			return "", false, true
+		}
+	}
+
+	return name, squash, false
+}
+
+// defaultValue parses the default tag of a field using the same rules as argument and environment values, checking
+// that the result can be converted to the field's type. String fields keep the tag as it is
+func defaultValue(tag string, fieldType reflect.Type) (interface{}, error) {
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+
+	var value interface{} = tag
+	if fieldType.Kind() != reflect.String {
+		value = parseString(tag)
+	}
+
+	// Structures are left to the decoder, since the getters can't convert to them
+	if containsStructure(fieldType) {
+		return value, nil
+	}
+
+	_, err := coerceValue(value, fieldType)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// containsStructure determines if the supplied type is a structure, or a slice or map that contains them
+func containsStructure(valueType reflect.Type) bool {
+	switch valueType.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return containsStructure(valueType.Elem())
+	case reflect.Struct:
+		return valueType != timeType
+	default:
+		return false
+	}
+}
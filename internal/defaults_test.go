@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type defaultsDatabase struct {
+	Host    string        `default:"localhost"`
+	Port    int           `default:"5432"`
+	Timeout time.Duration `default:"30s"`
+	User    string
+}
+
+type defaultsLogging struct {
+	Level string `mapstructure:"level" default:"info"`
+}
+
+type defaultsStructure struct {
+	defaultsLogging `mapstructure:",squash"`
+	Name            string           `mapstructure:"name" default:"1.10"`
+	Hosts           []string         `mapstructure:"hosts" default:"[\"a\", \"b\"]"`
+	Ports           []int            `mapstructure:"ports" default:"[80, 443]"`
+	Limit           ByteSize         `mapstructure:"limit" default:"512MiB"`
+	Enabled         *bool            `mapstructure:"enabled" default:"true"`
+	Database        defaultsDatabase `mapstructure:"database"`
+	Replica         *defaultsDatabase
+	Ignored         string `mapstructure:"-" default:"ignored"`
+	Started         time.Time
+	unexported      string
+}
+
+func TestDefaultsLoader(t *testing.T) {
+
+	Convey("Loads the defaults declared on a structure", t, func() {
+		values, err := NewDefaultsLoader(&defaultsStructure{}).Load()
+		So(err, ShouldBeNil)
+		So(values, ShouldResemble, map[string]interface{}{
+			"level":   "info",
+			"name":    "1.10",
+			"hosts":   []interface{}{"a", "b"},
+			"ports":   []interface{}{float64(80), float64(443)},
+			"limit":   "512MiB",
+			"enabled": true,
+			"database": map[string]interface{}{
+				"Host":    "localhost",
+				"Port":    5432,
+				"Timeout": 30 * time.Second,
+			},
+			"Replica": map[string]interface{}{
+				"Host":    "localhost",
+				"Port":    5432,
+				"Timeout": 30 * time.Second,
+			},
+		})
+	})
+
+	Convey("Returns an error for a default that can't be converted to the field's type", t, func() {
+		_, err := NewDefaultsLoader(struct {
+			Database struct {
+				Port int `default:"many"`
+			}
+		}{}).Load()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "invalid default for key 'Database:Port'")
+	})
+
+	Convey("Returns an error when the value isn't a structure", t, func() {
+		_, err := NewDefaultsLoader(map[string]interface{}{}).Load()
+		So(err, ShouldNotBeNil)
+
+		_, err = NewDefaultsLoader(nil).Load()
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Reports its source as the structure type", t, func() {
+		config := NewConfig()
+		config.Use(NewDefaultsLoader(defaultsStructure{}))
+
+		source, err := config.Source("database:Port")
+		So(source, ShouldResemble, Source{Kind: "defaults", Name: "internal.defaultsStructure", Index: 0})
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestToStructureDefaults(t *testing.T) {
+
+	Convey("Fills fields that no loader supplied with their defaults", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{
+			"level":    "debug",
+			"ports":    []interface{}{8080},
+			"database": map[string]interface{}{"port": 6543, "User": "admin"},
+		}))
+
+		structure := defaultsStructure{}
+		So(config.ToStructure(&structure), ShouldBeNil)
+		So(structure.Level, ShouldEqual, "debug")
+		So(structure.Name, ShouldEqual, "1.10")
+		So(structure.Hosts, ShouldResemble, []string{"a", "b"})
+		So(structure.Ports, ShouldResemble, []int{8080})
+		So(structure.Limit, ShouldEqual, 512*Mebibyte)
+		So(*structure.Enabled, ShouldBeTrue)
+		So(structure.Database, ShouldResemble, defaultsDatabase{Host: "localhost", Port: 6543, Timeout: 30 * time.Second, User: "admin"})
+		So(structure.Replica, ShouldResemble, &defaultsDatabase{Host: "localhost", Port: 5432, Timeout: 30 * time.Second})
+		So(structure.Ignored, ShouldBeEmpty)
+	})
+
+	Convey("Doesn't add defaults to the configuration", t, func() {
+		config := NewConfig()
+		So(config.ToStructure(&defaultsStructure{}), ShouldBeNil)
+		So(config.Map(), ShouldBeEmpty)
+	})
+
+	Convey("Returns an error for an invalid default", t, func() {
+		structure := struct {
+			Timeout time.Duration `default:"soon"`
+		}{}
+		So(NewConfig().ToStructure(&structure), ShouldNotBeNil)
+	})
+
+	Convey("Still copies to structures without defaults and to maps", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"key": "value"}))
+
+		m := map[string]interface{}{}
+		So(config.ToStructure(&m), ShouldBeNil)
+		So(m, ShouldResemble, map[string]interface{}{"key": "value"})
+	})
+}
@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// redacted replaces the values of sensitive keys in dumps
+const redacted = "******"
+
+// defaultSensitivePatterns defines the key patterns that are redacted in dumps unless configured otherwise
+var defaultSensitivePatterns = []string{"password", "token", "secret"}
+
+// DumpOption defines an option that changes how a configuration is dumped
+type DumpOption func(*dumpOptions)
+
+// dumpOptions defines the configurable behaviour of dumping a configuration, along with the values before
+// interpolation when the configuration is interpolated
+type dumpOptions struct {
+	patterns  []string
+	sensitive [][]string
+	raw       map[string]interface{}
+	delimiter string
+	fold      bool
+}
+
+// WithSensitivePatterns replaces the patterns that mark keys as sensitive (password, token and secret by default). A
+// key is sensitive if any of its segments contains one of the patterns, ignoring case
+func WithSensitivePatterns(patterns ...string) DumpOption {
+	return func(options *dumpOptions) {
+		options.patterns = patterns
+	}
+}
+
+// WithSensitiveFields marks the keys of the fields in the supplied structure that have a `sensitive:"true"` tag as
+// sensitive
+func WithSensitiveFields(structure interface{}) DumpOption {
+	return func(options *dumpOptions) {
+		options.sensitive = append(options.sensitive, sensitiveFields(reflect.TypeOf(structure), []string{})...)
+	}
+}
+
+// Dump formats the loaded configuration for printing, usually as JSON, YAML or sorted `key=value` lines. Durations are
+// written in their human form (e.g. 1m30s), and the values of sensitive keys are redacted, as are interpolated values
+// that reference sensitive keys. Secret references aren't resolved
+func (config *Config) Dump(format Format, options ...DumpOption) ([]byte, error) {
+	appliedOptions := dumpOptions{patterns: defaultSensitivePatterns}
+	for _, option := range options {
+		option(&appliedOptions)
+	}
+
+	currentState := config.current()
+	if config.options.interpolate {
+		appliedOptions.raw = currentState.rawValues()
+		appliedOptions.delimiter = config.options.delimiter
+		appliedOptions.fold = config.options.caseInsensitive
+	}
+
+	values := printableValue(currentState.values, []string{}, appliedOptions.redact).(map[string]interface{})
+	return encode(values, format, config.options.delimiter)
+}
+
+// redact replaces the supplied value, found at the supplied keys, if it's sensitive or interpolated from a sensitive key
+func (options dumpOptions) redact(value interface{}, keys []string) interface{} {
+	if value != nil && (options.isSensitive(keys) || options.interpolatesSensitive(keys)) {
+		return redacted
+	}
+	return value
+}
+
+// isSensitive determines if the value at the supplied keys should be redacted
+func (options dumpOptions) isSensitive(keys []string) bool {
+	for _, key := range keys {
+		for _, pattern := range options.patterns {
+			if len(pattern) > 0 && strings.Contains(strings.ToLower(key), strings.ToLower(pattern)) {
+				return true
+			}
+		}
+	}
+
+	for _, sensitiveKeys := range options.sensitive {
+		if matchesFieldKeys(keys, sensitiveKeys) {
+			return true
+		}
+	}
+	return false
+}
+
+// interpolatesSensitive determines if the value at the supplied keys was interpolated from a reference to a sensitive
+// key, either directly or through other references, so that secrets can't leak through keys that aren't sensitive
+func (options dumpOptions) interpolatesSensitive(keys []string) bool {
+	if options.raw == nil {
+		return false
+	}
+
+	// The value may have come from a reference to a whole map or slice, at any of its parent keys
+	for i := 1; i <= len(keys); i++ {
+		rawValue, err := getPath(options.raw, keys[:i], options.fold)
+		if err != nil {
+			return false
+		}
+
+		rawString, isString := rawValue.(string)
+		if isString && options.referencesSensitive(rawString, map[string]bool{}) {
+			return true
+		}
+	}
+	return false
+}
+
+// referencesSensitive determines if the supplied raw string contains a reference to a sensitive key
+func (options dumpOptions) referencesSensitive(value string, seen map[string]bool) bool {
+	for i := 0; i < len(value); {
+		start := strings.Index(value[i:], "${")
+		if start < 0 {
+			return false
+		}
+		start += i
+
+		end := referenceEnd(value, start+2)
+		if end < 0 {
+			return false
+		}
+
+		// Escaped references aren't interpolated
+		escaped := start > 0 && value[start-1] == '$'
+		if !escaped && options.isSensitiveReference(value[start+2:end], seen) {
+			return true
+		}
+		i = end + 1
+	}
+	return false
+}
+
+// isSensitiveReference determines if a single reference, or its default, resolves to anything sensitive. Environment
+// variables are sensitive if their name matches a pattern
+func (options dumpOptions) isSensitiveReference(reference string, seen map[string]bool) bool {
+	name, defaultValue, hasDefault := strings.Cut(reference, ":-")
+	if hasDefault && options.referencesSensitive(defaultValue, seen) {
+		return true
+	}
+
+	if strings.HasPrefix(name, environmentPrefix) {
+		return options.isSensitive([]string{strings.TrimPrefix(name, environmentPrefix)})
+	}
+
+	keys := splitKey(name, options.delimiter)
+	if options.isSensitive(keys) {
+		return true
+	}
+
+	// Follow the reference, once, in case the referenced value contains sensitive keys or references of its own
+	id := joinKey(keys, options.delimiter)
+	if options.fold {
+		id = strings.ToLower(id)
+	}
+	if seen[id] {
+		return false
+	}
+	seen[id] = true
+
+	referenced, err := getPath(options.raw, keys, options.fold)
+	if err != nil {
+		return false
+	}
+	return options.containsSensitive(referenced, keys, seen)
+}
+
+// containsSensitive determines if the supplied raw value, found at the supplied keys, has any sensitive keys below it
+// or references anything sensitive
+func (options dumpOptions) containsSensitive(value interface{}, keys []string, seen map[string]bool) bool {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, element := range typedValue {
+			elementKeys := append(keys[:len(keys):len(keys)], key)
+			if (element != nil && options.isSensitive(elementKeys)) || options.containsSensitive(element, elementKeys, seen) {
+				return true
+			}
+		}
+	case []interface{}:
+		for i, element := range typedValue {
+			elementKeys := append(keys[:len(keys):len(keys)], strconv.Itoa(i))
+			if (element != nil && options.isSensitive(elementKeys)) || options.containsSensitive(element, elementKeys, seen) {
+				return true
+			}
+		}
+	case string:
+		return options.referencesSensitive(typedValue, seen)
+	}
+	return false
+}
+
+// matchesFieldKeys determines if the supplied keys are at or below the keys of a field. Field keys match regardless
+// of case, the same way mapstructure matches them, and * matches any slice index or map key
+func matchesFieldKeys(keys []string, fieldKeys []string) bool {
+	if len(keys) < len(fieldKeys) {
+		return false
+	}
+
+	for i, fieldKey := range fieldKeys {
+		if fieldKey != "*" && !strings.EqualFold(fieldKey, keys[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sensitiveFields returns the keys of the fields with a `sensitive:"true"` tag in the supplied type, keyed the same way
+// mapstructure keys the fields
+func sensitiveFields(valueType reflect.Type, keys []string) [][]string {
+	for valueType != nil && valueType.Kind() == reflect.Pointer {
+		valueType = valueType.Elem()
+	}
+	if valueType == nil || !containsStructure(valueType) {
+		return nil
+	}
+
+	switch valueType.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return sensitiveFields(valueType.Elem(), append(keys[:len(keys):len(keys)], "*"))
+	}
+
+	var sensitive [][]string
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		name, squash, skip := fieldKey(field)
+		if skip || (!field.IsExported() && !squash) {
+			continue
+		}
+
+		fieldKeys := append(keys[:len(keys):len(keys)], name)
+		if squash {
+			fieldKeys = keys
+		}
+
+		isSensitive, _ := strconv.ParseBool(field.Tag.Get("sensitive"))
+		if isSensitive {
+			sensitive = append(sensitive, fieldKeys)
+			continue
+		}
+		sensitive = append(sensitive, sensitiveFields(field.Type, fieldKeys)...)
+	}
+	return sensitive
+}
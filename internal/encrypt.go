@@ -0,0 +1,363 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Encrypted values are written as `ENC[ALGORITHM,base64 data]`
+const (
+	encryptedPrefix = "ENC["
+	encryptedSuffix = "]"
+	aesAlgorithm    = "AES256_GCM"
+	ageAlgorithm    = "AGE"
+	agePrefix       = "AGE-SECRET-KEY-"
+	aesKeyLength    = 32
+	ageKeyPathEnd   = "\x00"
+)
+
+// KeySource defines a function that supplies the keys used to decrypt configuration values. The keys are one per
+// line: base64 encoded 32 byte AES keys, or age identities (`AGE-SECRET-KEY-1...`). Blank lines and lines starting
+// with # are ignored
+type KeySource func() ([]byte, error)
+
+// KeyFromFile creates a key source that reads the keys from a file
+func KeyFromFile(filePath string) KeySource {
+	return func() ([]byte, error) {
+		return os.ReadFile(filePath)
+	}
+}
+
+// KeyFromEnvironment creates a key source that reads the keys from an environment variable
+func KeyFromEnvironment(name string) KeySource {
+	return func() ([]byte, error) {
+		keys, found := os.LookupEnv(name)
+		if !found {
+			return nil, fmt.Errorf("environment variable '%s' is not set", name)
+		}
+		return []byte(keys), nil
+	}
+}
+
+// DecryptingLoader defines a loader that wraps another loader, decrypting the encrypted values in its configuration
+type DecryptingLoader struct {
+	loader Loader
+	keys   KeySource
+}
+
+// NewDecryptingLoader creates a new decrypting loader wrapping the supplied loader, decrypting values with the keys
+// from the supplied source
+func NewDecryptingLoader(loader Loader, keys KeySource) *DecryptingLoader {
+	return &DecryptingLoader{
+		loader: loader,
+		keys:   keys,
+	}
+}
+
+// Load loads the wrapped loader and decrypts every encrypted value in the result. Values that fail to decrypt, such
+// as values that have been tampered with, fail the whole load
+func (loader *DecryptingLoader) Load() (map[string]interface{}, error) {
+	loadedMap, _, err := loader.loadWithNames()
+	return loadedMap, err
+}
+
+// loadWithNames loads and decrypts the wrapped loader, along with the per-key names it records
+func (loader *DecryptingLoader) loadWithNames() (map[string]interface{}, map[string]interface{}, error) {
+	loadedMap, names, err := loadWithNames(loader.loader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Only read the keys when they're needed, so configurations without encrypted values don't require them
+	if !containsEncrypted(loadedMap) {
+		return loadedMap, names, nil
+	}
+
+	keyData, err := loader.keys()
+	if err != nil {
+		// Not wrapped, so a missing key file can't be mistaken for a missing configuration file by the optional loader
+		return nil, nil, fmt.Errorf("failed to read decryption keys: %v", err)
+	}
+	keys, err := parseKeys(keyData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	decrypted, err := keys.decryptValue(loadedMap, []string{})
+	if err != nil {
+		return nil, nil, err
+	}
+	return decrypted.(map[string]interface{}), names, nil
+}
+
+// decryptionKeys defines the keys parsed from a key source
+type decryptionKeys struct {
+	aes        [][]byte
+	identities []age.Identity
+}
+
+// parseKeys parses the AES keys and age identities from the data supplied by a key source
+func parseKeys(data []byte) (*decryptionKeys, error) {
+	keys := &decryptionKeys{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for line := 1; scanner.Scan(); line++ {
+		key := strings.TrimSpace(scanner.Text())
+		switch {
+		case len(key) == 0 || strings.HasPrefix(key, "#"):
+			continue
+		case strings.HasPrefix(key, agePrefix):
+			identity, err := age.ParseX25519Identity(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid age identity on line %d: %w", line, err)
+			}
+			keys.identities = append(keys.identities, identity)
+		default:
+			aesKey, err := base64.StdEncoding.DecodeString(key)
+			if err != nil || len(aesKey) != aesKeyLength {
+				return nil, fmt.Errorf("invalid key on line %d: expected a base64 encoded %d byte key", line, aesKeyLength)
+			}
+			keys.aes = append(keys.aes, aesKey)
+		}
+	}
+
+	if len(keys.aes) == 0 && len(keys.identities) == 0 {
+		return nil, errors.New("no decryption keys found")
+	}
+	return keys, scanner.Err()
+}
+
+// decryptValue decrypts the encrypted values in the supplied value, which is found at the supplied keys
+func (keys *decryptionKeys) decryptValue(value interface{}, path []string) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		decrypted := make(map[string]interface{}, len(typedValue))
+		for key, element := range typedValue {
+			decryptedElement, err := keys.decryptValue(element, append(path[:len(path):len(path)], key))
+			if err != nil {
+				return nil, err
+			}
+			decrypted[key] = decryptedElement
+		}
+		return decrypted, nil
+	case []interface{}:
+		decrypted := make([]interface{}, len(typedValue))
+		for i, element := range typedValue {
+			decryptedElement, err := keys.decryptValue(element, append(path[:len(path):len(path)], strconv.Itoa(i)))
+			if err != nil {
+				return nil, err
+			}
+			decrypted[i] = decryptedElement
+		}
+		return decrypted, nil
+	case string:
+		if !isEncrypted(typedValue) {
+			return value, nil
+		}
+		decrypted, err := keys.decrypt(typedValue, joinKey(path, defaultDelimiter))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key '%s': %w", joinKey(path, defaultDelimiter), err)
+		}
+		return decrypted, nil
+	default:
+		return value, nil
+	}
+}
+
+// decrypt decrypts a single encrypted value, found at the supplied key path
+func (keys *decryptionKeys) decrypt(value string, keyPath string) (string, error) {
+	algorithm, encoded, found := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(value, encryptedPrefix), encryptedSuffix), ",")
+	if !found {
+		return "", errors.New("encrypted value is missing its algorithm")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("encrypted value is not valid base64: %w", err)
+	}
+
+	switch algorithm {
+	case aesAlgorithm:
+		return keys.decryptAES(data, keyPath)
+	case ageAlgorithm:
+		return keys.decryptAge(data, keyPath)
+	default:
+		return "", fmt.Errorf("unsupported encryption algorithm '%s'", algorithm)
+	}
+}
+
+// decryptAES decrypts AES-256-GCM data, which starts with its nonce. The key path is authenticated as additional data,
+// so values that were moved to another key fail to decrypt. Every key is tried in turn
+func (keys *decryptionKeys) decryptAES(data []byte, keyPath string) (string, error) {
+	if len(keys.aes) == 0 {
+		return "", errors.New("no AES key was supplied")
+	}
+
+	for _, key := range keys.aes {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", err
+		}
+		if len(data) < gcm.NonceSize() {
+			return "", errors.New("encrypted value is too short")
+		}
+
+		plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(keyPath))
+		if err == nil {
+			return string(plaintext), nil
+		}
+	}
+
+	return "", errors.New("the value was modified, moved from another key or encrypted with a different key")
+}
+
+// decryptAge decrypts age data with the supplied identities. The data starts with the key path it was encrypted for,
+// which is checked against the key path it was found at so values that were moved to another key fail to decrypt
+func (keys *decryptionKeys) decryptAge(data []byte, keyPath string) (string, error) {
+	if len(keys.identities) == 0 {
+		return "", errors.New("no age identity was supplied")
+	}
+
+	reader, err := age.Decrypt(bytes.NewReader(data), keys.identities...)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("the value was modified: %w", err)
+	}
+
+	encryptedKeyPath, value, found := strings.Cut(string(plaintext), ageKeyPathEnd)
+	if !found || encryptedKeyPath != keyPath {
+		return "", errors.New("the value was moved from another key")
+	}
+	return value, nil
+}
+
+// GenerateKey generates a random AES key, base64 encoded the way key sources supply it
+func GenerateKey() (string, error) {
+	key := make([]byte, aesKeyLength)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// EncryptValue encrypts a value with a base64 encoded AES key, returning it in the `ENC[AES256_GCM,...]` form that
+// can be written to a configuration file. The value is tied to the key path it's written at (e.g. `database:password`),
+// and fails to decrypt anywhere else. The key path is always written with the default delimiter, whatever delimiter
+// the configuration uses, and is the path in the loader the decrypted loader wraps: the path inside the file, or the
+// path left after an argument or environment loader trims its prefix
+func EncryptValue(value string, keyPath string, key string) (string, error) {
+	aesKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(aesKey) != aesKeyLength {
+		return "", fmt.Errorf("expected a base64 encoded %d byte key", aesKeyLength)
+	}
+
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	additionalData := []byte(encryptionKeyPath(keyPath))
+	return encryptedValue(aesAlgorithm, gcm.Seal(nonce, nonce, []byte(value), additionalData)), nil
+}
+
+// EncryptAgeValue encrypts a value for the supplied age recipients (`age1...`), returning it in the `ENC[AGE,...]`
+// form that can be written to a configuration file. The value is tied to its key path the same way as EncryptValue
+func EncryptAgeValue(value string, keyPath string, recipients ...string) (string, error) {
+	if len(recipients) == 0 {
+		return "", errors.New("no age recipients were supplied")
+	}
+
+	parsedRecipients := make([]age.Recipient, len(recipients))
+	for i, recipient := range recipients {
+		parsedRecipient, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return "", err
+		}
+		parsedRecipients[i] = parsedRecipient
+	}
+
+	encrypted := &bytes.Buffer{}
+	writer, err := age.Encrypt(encrypted, parsedRecipients...)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.WriteString(writer, encryptionKeyPath(keyPath)+ageKeyPathEnd+value)
+	if err != nil {
+		return "", err
+	}
+	err = writer.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedValue(ageAlgorithm, encrypted.Bytes()), nil
+}
+
+// encryptionKeyPath normalizes the key path a value is encrypted for, joining it with the default delimiter the way
+// the decrypting loader does
+func encryptionKeyPath(keyPath string) string {
+	return joinKey(splitKey(keyPath, defaultDelimiter), defaultDelimiter)
+}
+
+// newGCM creates an AES-GCM cipher from the supplied key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptedValue formats encrypted data as an `ENC[ALGORITHM,base64 data]` value
+func encryptedValue(algorithm string, data []byte) string {
+	return encryptedPrefix + algorithm + "," + base64.StdEncoding.EncodeToString(data) + encryptedSuffix
+}
+
+// isEncrypted determines if the supplied string is an encrypted value
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// containsEncrypted determines if the supplied value contains any encrypted values
+func containsEncrypted(value interface{}) bool {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for _, element := range typedValue {
+			if containsEncrypted(element) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, element := range typedValue {
+			if containsEncrypted(element) {
+				return true
+			}
+		}
+	case string:
+		return isEncrypted(typedValue)
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// testKeys returns a key source that supplies the supplied keys
+func testKeys(keys ...string) KeySource {
+	return func() ([]byte, error) {
+		return []byte(strings.Join(keys, "\n")), nil
+	}
+}
+
+func TestEncryptValue(t *testing.T) {
+
+	Convey("Encrypts a value that decrypts back to the original", t, func() {
+		key, err := GenerateKey()
+		So(err, ShouldBeNil)
+
+		encrypted, err := EncryptValue("hunter2", "database:password", key)
+		So(err, ShouldBeNil)
+		So(encrypted, ShouldStartWith, "ENC[AES256_GCM,")
+		So(encrypted, ShouldNotContainSubstring, "hunter2")
+
+		keys, err := parseKeys([]byte(key))
+		So(err, ShouldBeNil)
+		decrypted, err := keys.decrypt(encrypted, "database:password")
+		So(decrypted, ShouldEqual, "hunter2")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Ties the value to its key path", t, func() {
+		key, _ := GenerateKey()
+		keys, _ := parseKeys([]byte(key))
+
+		encrypted, err := EncryptValue("hunter2", "database:password", key)
+		So(err, ShouldBeNil)
+		_, err = keys.decrypt(encrypted, "database:user")
+		So(err, ShouldNotBeNil)
+
+		encrypted, err = EncryptValue("hunter2", `"database":password`, key)
+		So(err, ShouldBeNil)
+		decrypted, err := keys.decrypt(encrypted, "database:password")
+		So(decrypted, ShouldEqual, "hunter2")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Uses a new nonce for every value", t, func() {
+		key, _ := GenerateKey()
+		first, _ := EncryptValue("hunter2", "password", key)
+		second, _ := EncryptValue("hunter2", "password", key)
+		So(first, ShouldNotEqual, second)
+	})
+
+	Convey("Returns an error for invalid keys", t, func() {
+		_, err := EncryptValue("hunter2", "password", "not a key")
+		So(err, ShouldNotBeNil)
+
+		_, err = EncryptValue("hunter2", "password", base64.StdEncoding.EncodeToString([]byte("short")))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestEncryptAgeValue(t *testing.T) {
+
+	Convey("Encrypts a value for age recipients", t, func() {
+		identity, err := age.GenerateX25519Identity()
+		So(err, ShouldBeNil)
+
+		encrypted, err := EncryptAgeValue("hunter2", "password", identity.Recipient().String())
+		So(err, ShouldBeNil)
+		So(encrypted, ShouldStartWith, "ENC[AGE,")
+
+		keys, err := parseKeys([]byte(identity.String()))
+		So(err, ShouldBeNil)
+		decrypted, err := keys.decrypt(encrypted, "password")
+		So(decrypted, ShouldEqual, "hunter2")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Ties the value to its key path", t, func() {
+		identity, _ := age.GenerateX25519Identity()
+		keys, _ := parseKeys([]byte(identity.String()))
+
+		encrypted, err := EncryptAgeValue("hunter2", "database:password", identity.Recipient().String())
+		So(err, ShouldBeNil)
+		_, err = keys.decrypt(encrypted, "database:user")
+		So(err.Error(), ShouldEqual, "the value was moved from another key")
+
+		encrypted, err = EncryptAgeValue("hunter2", `"database":password`, identity.Recipient().String())
+		So(err, ShouldBeNil)
+		decrypted, err := keys.decrypt(encrypted, "database:password")
+		So(decrypted, ShouldEqual, "hunter2")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns an error without valid recipients", t, func() {
+		_, err := EncryptAgeValue("hunter2", "password")
+		So(err, ShouldNotBeNil)
+
+		_, err = EncryptAgeValue("hunter2", "password", "age1invalid")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestParseKeys(t *testing.T) {
+
+	Convey("Parses AES keys and age identities, ignoring comments", t, func() {
+		aesKey, _ := GenerateKey()
+		identity, _ := age.GenerateX25519Identity()
+
+		keys, err := parseKeys([]byte("# keys\n\n" + aesKey + "\n  " + identity.String() + "  \n"))
+		So(err, ShouldBeNil)
+		So(keys.aes, ShouldHaveLength, 1)
+		So(keys.identities, ShouldHaveLength, 1)
+	})
+
+	Convey("Returns an error for invalid keys", t, func() {
+		_, err := parseKeys([]byte("# comment\nnot a key"))
+		So(err.Error(), ShouldEqual, "invalid key on line 2: expected a base64 encoded 32 byte key")
+
+		_, err = parseKeys([]byte("AGE-SECRET-KEY-1INVALID"))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Returns an error if there are no keys", t, func() {
+		_, err := parseKeys([]byte("# no keys here\n"))
+		So(err.Error(), ShouldEqual, "no decryption keys found")
+	})
+}
+
+func TestKeySources(t *testing.T) {
+
+	Convey("Reads keys from files", t, func() {
+		filePath := filepath.Join(t.TempDir(), "key")
+		So(os.WriteFile(filePath, []byte("key"), 0o600), ShouldBeNil)
+
+		keys, err := KeyFromFile(filePath)()
+		So(string(keys), ShouldEqual, "key")
+		So(err, ShouldBeNil)
+
+		_, err = KeyFromFile(filePath + ".missing")()
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Reads keys from environment variables", t, func() {
+		t.Setenv("GCONF_ENCRYPT_TEST_KEY", "key")
+
+		keys, err := KeyFromEnvironment("GCONF_ENCRYPT_TEST_KEY")()
+		So(string(keys), ShouldEqual, "key")
+		So(err, ShouldBeNil)
+
+		_, err = KeyFromEnvironment("GCONF_ENCRYPT_TEST_MISSING")()
+		So(err.Error(), ShouldEqual, "environment variable 'GCONF_ENCRYPT_TEST_MISSING' is not set")
+	})
+}
+
+func TestDecryptingLoad(t *testing.T) {
+	key, _ := GenerateKey()
+	otherKey, _ := GenerateKey()
+	identity, _ := age.GenerateX25519Identity()
+	password, _ := EncryptValue("hunter2", "database:password", key)
+	user, _ := EncryptValue("admin", "database:user", key)
+	token, _ := EncryptAgeValue("abc123", "tokens:0", identity.Recipient().String())
+
+	Convey("Decrypts encrypted values anywhere in the loaded configuration", t, func() {
+		loader := NewDecryptingLoader(NewMapLoader(map[string]interface{}{
+			"database": map[string]interface{}{"host": "localhost", "password": password},
+			"tokens":   []interface{}{token, "plain"},
+		}), testKeys(otherKey, key, identity.String()))
+
+		result, err := loader.Load()
+		So(err, ShouldBeNil)
+		So(result, ShouldResemble, map[string]interface{}{
+			"database": map[string]interface{}{"host": "localhost", "password": "hunter2"},
+			"tokens":   []interface{}{"abc123", "plain"},
+		})
+	})
+
+	Convey("Doesn't read the keys if there's nothing to decrypt", t, func() {
+		loader := NewDecryptingLoader(NewMapLoader(map[string]interface{}{"host": "localhost"}), KeyFromEnvironment("GCONF_ENCRYPT_TEST_MISSING"))
+		result, err := loader.Load()
+		So(result, ShouldResemble, map[string]interface{}{"host": "localhost"})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Fails if a value was tampered with", t, func() {
+		data, _ := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(password, "ENC[AES256_GCM,"), "]"))
+		data[len(data)-1] ^= 1
+		tampered := encryptedValue(aesAlgorithm, data)
+
+		loader := NewDecryptingLoader(NewMapLoader(map[string]interface{}{
+			"database": map[string]interface{}{"password": tampered},
+		}), testKeys(key))
+		_, err := loader.Load()
+		So(err.Error(), ShouldEqual, "failed to decrypt key 'database:password': the value was modified, moved from another key "+
+			"or encrypted with a different key")
+	})
+
+	Convey("Fails if values were swapped between keys", t, func() {
+		loader := NewDecryptingLoader(NewMapLoader(map[string]interface{}{
+			"database": map[string]interface{}{"user": password, "password": user},
+		}), testKeys(key))
+		_, err := loader.Load()
+		So(err, ShouldNotBeNil)
+
+		loader = NewDecryptingLoader(NewMapLoader(map[string]interface{}{
+			"database": map[string]interface{}{"user": user, "password": password},
+		}), testKeys(key))
+		result, err := loader.Load()
+		So(err, ShouldBeNil)
+		So(result, ShouldResemble, map[string]interface{}{"database": map[string]interface{}{"user": "admin", "password": "hunter2"}})
+	})
+
+	Convey("Fails if the value was encrypted with a different key", t, func() {
+		loader := NewDecryptingLoader(NewMapLoader(map[string]interface{}{
+			"database": map[string]interface{}{"password": password},
+		}), testKeys(otherKey))
+		_, err := loader.Load()
+		So(err, ShouldNotBeNil)
+
+		loader = NewDecryptingLoader(NewMapLoader(map[string]interface{}{"token": token}), testKeys(key))
+		_, err = loader.Load()
+		So(err.Error(), ShouldEqual, "failed to decrypt key 'token': no age identity was supplied")
+	})
+
+	Convey("Fails for malformed values and unknown algorithms", t, func() {
+		for _, value := range []string{"ENC[AES256_GCM]", "ENC[AES256_GCM,!!!]", "ENC[AES256_GCM,AAAA]", "ENC[ROT13,AAAA]"} {
+			loader := NewDecryptingLoader(NewMapLoader(map[string]interface{}{"password": value}), testKeys(key))
+			_, err := loader.Load()
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Fails if the keys can't be read", t, func() {
+		loader := NewDecryptingLoader(NewMapLoader(map[string]interface{}{"password": password}), KeyFromEnvironment("GCONF_ENCRYPT_TEST_MISSING"))
+		_, err := loader.Load()
+		So(err.Error(), ShouldEqual, "failed to read decryption keys: environment variable 'GCONF_ENCRYPT_TEST_MISSING' is not set")
+	})
+
+	Convey("Fails even when optional if the key file is missing", t, func() {
+		filePath := filepath.Join(t.TempDir(), "config.yaml")
+		So(os.WriteFile(filePath, []byte("database:\n  password: "+password+"\n"), 0o600), ShouldBeNil)
+
+		missingKeys := KeyFromFile(filepath.Join(t.TempDir(), "missing.key"))
+		result, err := NewOptionalLoader(NewDecryptingLoader(NewYAMLFileLoader(filePath, false), missingKeys)).Load()
+		So(result, ShouldBeNil)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "failed to read decryption keys: ")
+	})
+
+	Convey("Returns errors from the wrapped loader, so it can be made optional", t, func() {
+		cause := errors.New("boom")
+		_, err := NewDecryptingLoader(&failingLoader{err: cause}, testKeys(key)).Load()
+		So(err, ShouldEqual, cause)
+
+		result, err := NewOptionalLoader(NewDecryptingLoader(NewYAMLFileLoader("missing.yaml", false), testKeys(key))).Load()
+		So(result, ShouldResemble, map[string]interface{}{})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Checks the key path left after the wrapped loader trims its prefix", t, func() {
+		t.Setenv("GCONF_ENCRYPT_TEST_DATABASE__PASSWORD", password)
+		result, err := NewDecryptingLoader(NewEnvironmentLoader(true, "__", "GCONF_ENCRYPT_TEST_"), testKeys(key)).Load()
+		So(err, ShouldBeNil)
+		So(result, ShouldResemble, map[string]interface{}{"database": map[string]interface{}{"password": "hunter2"}})
+	})
+
+	Convey("Is described as the wrapped loader", t, func() {
+		kind, name := describeLoader(NewDecryptingLoader(NewYAMLFileLoader("config.yaml", false), testKeys(key)))
+		So(kind, ShouldEqual, "YAML file")
+		So(name, ShouldEqual, "config.yaml")
+	})
+
+	Convey("Decrypts values loaded from files", t, func() {
+		filePath := filepath.Join(t.TempDir(), "config.yaml")
+		So(os.WriteFile(filePath, []byte("database:\n  password: "+password+"\n"), 0o600), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewDecryptingLoader(NewYAMLFileLoader(filePath, false), testKeys(key)))
+		value, err := config.GetString("database:password")
+		So(value, ShouldEqual, "hunter2")
+		So(err, ShouldBeNil)
+	})
+}
@@ -23,12 +23,20 @@ func NewEnvironmentLoader(lowerCase bool, separator string, prefix string) *Envi
 
 // Load loads environment variables
 func (loader *EnvironmentLoader) Load() (map[string]interface{}, error) {
+	config, _, err := loader.loadWithNames()
+	return config, err
+}
+
+// loadWithNames loads environment variables, along with a map of the variable each key was loaded from
+func (loader *EnvironmentLoader) loadWithNames() (map[string]interface{}, map[string]interface{}, error) {
 	return loader.parseEnvironment(os.Environ())
 }
 
-// parseEnvironment parses environment variables into a configuration map
-func (loader *EnvironmentLoader) parseEnvironment(environmentData []string) (map[string]interface{}, error) {
+// parseEnvironment parses environment variables into a configuration map, returning the variable names by key
+// alongside it
+func (loader *EnvironmentLoader) parseEnvironment(environmentData []string) (map[string]interface{}, map[string]interface{}, error) {
 	config := map[string]interface{}{}
+	names := map[string]interface{}{}
 
 	for _, environmentLine := range environmentData {
 
@@ -69,9 +77,12 @@ func (loader *EnvironmentLoader) parseEnvironment(environmentData []string) (map
 		value := parseString(keyValue[1])
 		_, err := set(config, separatedKeys, value)
 		if err != nil {
-			return config, err
+			return config, names, err
 		}
+
+		// Record the variable name so the value's source can be reported
+		_, _ = set(names, separatedKeys, keyValue[0])
 	}
 
-	return config, nil
+	return config, names, nil
 }
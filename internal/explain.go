@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Candidate defines a value supplied for a key by a single loader
+type Candidate struct {
+	Value  interface{}
+	Source Source
+}
+
+// String formats the candidate for use in messages
+func (candidate Candidate) String() string {
+	return fmt.Sprintf("%v from %s", candidate.Value, candidate.Source)
+}
+
+// Explanation describes the effective value of a key, along with every value that it shadows
+type Explanation struct {
+	Key      string
+	Value    interface{}
+	Source   Source
+	Shadowed []Candidate
+}
+
+// String formats the explanation as the winning value followed by one line per shadowed candidate
+func (explanation *Explanation) String() string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("%s = %v", explanation.Key, Candidate{Value: explanation.Value, Source: explanation.Source}))
+	for _, candidate := range explanation.Shadowed {
+		builder.WriteString(fmt.Sprintf("\n  shadowed: %v", candidate))
+	}
+	return builder.String()
+}
+
+// Explain returns the effective value of the supplied key and its source, along with every value supplied by a lower
+// priority loader that was shadowed by it, in priority order
+func (config *Config) Explain(key string) (*Explanation, error) {
+	keys := config.splitKey(key)
+	currentState := config.current()
+	fold := config.options.caseInsensitive
+	value, err := getPath(currentState.values, keys, fold)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &Explanation{
+		Key:   key,
+		Value: copyValue(value),
+	}
+
+	winnerFound := false
+	_, winnerIsMap := value.(map[string]interface{})
+	for _, configLayer := range currentState.allLayers() {
+		layerValue, err := getPath(configLayer.values, keys, fold)
+		if err != nil {
+			continue
+		}
+
+		// The first layer containing the key is the winner
+		if !winnerFound {
+			winnerFound = true
+			explanation.Source = configLayer.sourceOf(keys, fold)
+			continue
+		}
+
+		// Maps are merged with the winning map rather than shadowed by it
+		_, layerValueIsMap := layerValue.(map[string]interface{})
+		if winnerIsMap && layerValueIsMap {
+			continue
+		}
+
+		explanation.Shadowed = append(explanation.Shadowed, Candidate{
+			Value:  copyValue(layerValue),
+			Source: configLayer.sourceOf(keys, fold),
+		})
+	}
+
+	if !winnerFound {
+		return nil, fmt.Errorf("no source recorded for key '%s'", key)
+	}
+
+	return explanation, nil
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExplain(t *testing.T) {
+	config := NewConfig()
+	config.Use(NewMapLoader(map[string]interface{}{"server": map[string]interface{}{"port": 8080}}))
+	config.Use(NewYAMLFileLoader("../test/test.yaml", false))
+	config.Use(NewMapLoader(map[string]interface{}{
+		"server": map[string]interface{}{"port": 80, "host": "localhost"},
+		"object": "not a map",
+	}))
+
+	Convey("Explains a value without shadowed candidates", t, func() {
+		explanation, err := config.Explain("server:host")
+		So(err, ShouldBeNil)
+		So(explanation, ShouldResemble, &Explanation{
+			Key:    "server:host",
+			Value:  "localhost",
+			Source: Source{Kind: "map", Index: 2},
+		})
+	})
+
+	Convey("Lists shadowed candidates in priority order", t, func() {
+		explanation, err := config.Explain("server:port")
+		So(err, ShouldBeNil)
+		So(explanation.Value, ShouldEqual, 8080)
+		So(explanation.Source, ShouldResemble, Source{Kind: "map", Index: 0})
+		So(explanation.Shadowed, ShouldResemble, []Candidate{
+			{Value: 80, Source: Source{Kind: "map", Index: 2}},
+		})
+	})
+
+	Convey("Lists values shadowed by a map", t, func() {
+		explanation, err := config.Explain("object")
+		So(err, ShouldBeNil)
+		So(explanation.Source, ShouldResemble, Source{Kind: "YAML file", Name: "../test/test.yaml", Index: 1})
+		So(explanation.Shadowed, ShouldResemble, []Candidate{
+			{Value: "not a map", Source: Source{Kind: "map", Index: 2}},
+		})
+	})
+
+	Convey("Doesn't list maps that were merged with the winning map", t, func() {
+		explanation, err := config.Explain("server")
+		So(err, ShouldBeNil)
+		So(explanation.Shadowed, ShouldBeEmpty)
+	})
+
+	Convey("Returns copies of the values that can't modify the configuration", t, func() {
+		explanation, err := config.Explain("server")
+		So(err, ShouldBeNil)
+		explanation.Value.(map[string]interface{})["port"] = 1
+
+		port, err := config.Get("server:port")
+		So(err, ShouldBeNil)
+		So(port, ShouldEqual, 8080)
+	})
+
+	Convey("Formats the explanation", t, func() {
+		explanation, err := config.Explain("server:port")
+		So(err, ShouldBeNil)
+		So(explanation.String(), ShouldEqual, "server:port = 8080 from map (position 0)\n  shadowed: 80 from map (position 2)")
+	})
+
+	Convey("Returns an error when the key doesn't exist", t, func() {
+		explanation, err := config.Explain("server:missing")
+		So(explanation, ShouldBeNil)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Returns an error when no source was recorded", t, func() {
+		explanation, err := newConfig(&state{values: map[string]interface{}{"one": 1}}, defaultOptions()).Explain("one")
+		So(explanation, ShouldBeNil)
+		So(err, ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// GetOr gets a key from the loaded configuration, converting it to the requested type. Returns the supplied default
+// value if the key doesn't exist or can't be converted
+func GetOr[T any](config *Config, key string, defaultValue T) T {
+	value, err := Get[T](config, key)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// MustGet gets a key from the loaded configuration, converting it to the requested type. Panics if the key doesn't
+// exist or can't be converted
+func MustGet[T any](config *Config, key string) T {
+	value, err := config.Get(key)
+	if err != nil {
+		panic(fmt.Errorf("failed to get key '%s': %w", key, err))
+	}
+
+	result, err := coerce[T](value)
+	if err != nil {
+		panic(&mustGetError{key: key, value: value, targetType: reflect.TypeOf(new(T)).Elem(), err: err})
+	}
+	return result
+}
+
+// mustGetError defines the error MustGet panics with when a value can't be converted. It includes the full key and
+// the type of the value that was found, but not the value itself, which could be a secret
+type mustGetError struct {
+	key        string
+	value      interface{}
+	targetType reflect.Type
+	err        error
+}
+
+// Error formats the error without the value
+func (err *mustGetError) Error() string {
+	reason := "unsupported conversion"
+	conversionError := &ConversionError{}
+	if errors.As(err.err, &conversionError) {
+		reason = conversionError.Reason
+	}
+	return fmt.Sprintf("key '%s' contains %T value, which can't be read as %s: %s",
+		err.key, err.value, err.targetType, reason)
+}
+
+// Unwrap returns the underlying conversion error
+func (err *mustGetError) Unwrap() error {
+	return err.err
+}
+
+// GetMapOr gets a map from the loaded configuration, returning the default value if that fails
+func (config *Config) GetMapOr(key string, defaultValue map[string]interface{}) map[string]interface{} {
+	return GetOr[map[string]interface{}](config, key, defaultValue)
+}
+
+// MustGetMap gets a map from the loaded configuration, panicking if that fails
+func (config *Config) MustGetMap(key string) map[string]interface{} {
+	return MustGet[map[string]interface{}](config, key)
+}
+
+// GetSliceOr gets a slice from the loaded configuration, returning the default value if that fails
+func (config *Config) GetSliceOr(key string, defaultValue []interface{}) []interface{} {
+	return GetOr[[]interface{}](config, key, defaultValue)
+}
+
+// MustGetSlice gets a slice from the loaded configuration, panicking if that fails
+func (config *Config) MustGetSlice(key string) []interface{} {
+	return MustGet[[]interface{}](config, key)
+}
+
+// GetStringSliceOr gets a string slice from the loaded configuration, returning the default value if that fails
+func (config *Config) GetStringSliceOr(key string, defaultValue []string) []string {
+	return GetOr[[]string](config, key, defaultValue)
+}
+
+// MustGetStringSlice gets a string slice from the loaded configuration, panicking if that fails
+func (config *Config) MustGetStringSlice(key string) []string {
+	return MustGet[[]string](config, key)
+}
+
+// GetStringOr gets a string from the loaded configuration, returning the default value if that fails
+func (config *Config) GetStringOr(key string, defaultValue string) string {
+	return GetOr[string](config, key, defaultValue)
+}
+
+// MustGetString gets a string from the loaded configuration, panicking if that fails
+func (config *Config) MustGetString(key string) string {
+	return MustGet[string](config, key)
+}
+
+// GetIntegerSliceOr gets a integer slice from the loaded configuration, returning the default value if that fails
+func (config *Config) GetIntegerSliceOr(key string, defaultValue []int) []int {
+	return GetOr[[]int](config, key, defaultValue)
+}
+
+// MustGetIntegerSlice gets a integer slice from the loaded configuration, panicking if that fails
+func (config *Config) MustGetIntegerSlice(key string) []int {
+	return MustGet[[]int](config, key)
+}
+
+// GetIntegerOr gets a integer from the loaded configuration, returning the default value if that fails
+func (config *Config) GetIntegerOr(key string, defaultValue int) int {
+	return GetOr[int](config, key, defaultValue)
+}
+
+// MustGetInteger gets a integer from the loaded configuration, panicking if that fails
+func (config *Config) MustGetInteger(key string) int {
+	return MustGet[int](config, key)
+}
+
+// GetBooleanSliceOr gets a boolean slice from the loaded configuration, returning the default value if that fails
+func (config *Config) GetBooleanSliceOr(key string, defaultValue []bool) []bool {
+	return GetOr[[]bool](config, key, defaultValue)
+}
+
+// MustGetBooleanSlice gets a boolean slice from the loaded configuration, panicking if that fails
+func (config *Config) MustGetBooleanSlice(key string) []bool {
+	return MustGet[[]bool](config, key)
+}
+
+// GetBooleanOr gets a boolean from the loaded configuration, returning the default value if that fails
+func (config *Config) GetBooleanOr(key string, defaultValue bool) bool {
+	return GetOr[bool](config, key, defaultValue)
+}
+
+// MustGetBoolean gets a boolean from the loaded configuration, panicking if that fails
+func (config *Config) MustGetBoolean(key string) bool {
+	return MustGet[bool](config, key)
+}
+
+// GetFloatSliceOr gets a float slice from the loaded configuration, returning the default value if that fails
+func (config *Config) GetFloatSliceOr(key string, defaultValue []float64) []float64 {
+	return GetOr[[]float64](config, key, defaultValue)
+}
+
+// MustGetFloatSlice gets a float slice from the loaded configuration, panicking if that fails
+func (config *Config) MustGetFloatSlice(key string) []float64 {
+	return MustGet[[]float64](config, key)
+}
+
+// GetFloatOr gets a float from the loaded configuration, returning the default value if that fails
+func (config *Config) GetFloatOr(key string, defaultValue float64) float64 {
+	return GetOr[float64](config, key, defaultValue)
+}
+
+// MustGetFloat gets a float from the loaded configuration, panicking if that fails
+func (config *Config) MustGetFloat(key string) float64 {
+	return MustGet[float64](config, key)
+}
+
+// GetDurationSliceOr gets a duration slice from the loaded configuration, returning the default value if that fails
+func (config *Config) GetDurationSliceOr(key string, defaultValue []time.Duration) []time.Duration {
+	return GetOr[[]time.Duration](config, key, defaultValue)
+}
+
+// MustGetDurationSlice gets a duration slice from the loaded configuration, panicking if that fails
+func (config *Config) MustGetDurationSlice(key string) []time.Duration {
+	return MustGet[[]time.Duration](config, key)
+}
+
+// GetDurationOr gets a duration from the loaded configuration, returning the default value if that fails
+func (config *Config) GetDurationOr(key string, defaultValue time.Duration) time.Duration {
+	return GetOr[time.Duration](config, key, defaultValue)
+}
+
+// MustGetDuration gets a duration from the loaded configuration, panicking if that fails
+func (config *Config) MustGetDuration(key string) time.Duration {
+	return MustGet[time.Duration](config, key)
+}
+
+// GetTimeSliceOr gets a time slice from the loaded configuration, returning the default value if that fails
+func (config *Config) GetTimeSliceOr(key string, defaultValue []time.Time) []time.Time {
+	return GetOr[[]time.Time](config, key, defaultValue)
+}
+
+// MustGetTimeSlice gets a time slice from the loaded configuration, panicking if that fails
+func (config *Config) MustGetTimeSlice(key string) []time.Time {
+	return MustGet[[]time.Time](config, key)
+}
+
+// GetTimeOr gets a time from the loaded configuration, returning the default value if that fails
+func (config *Config) GetTimeOr(key string, defaultValue time.Time) time.Time {
+	return GetOr[time.Time](config, key, defaultValue)
+}
+
+// MustGetTime gets a time from the loaded configuration, panicking if that fails
+func (config *Config) MustGetTime(key string) time.Time {
+	return MustGet[time.Time](config, key)
+}
+
+// GetByteSizeSliceOr gets a byte size slice from the loaded configuration, returning the default value if that fails
+func (config *Config) GetByteSizeSliceOr(key string, defaultValue []ByteSize) []ByteSize {
+	return GetOr[[]ByteSize](config, key, defaultValue)
+}
+
+// MustGetByteSizeSlice gets a byte size slice from the loaded configuration, panicking if that fails
+func (config *Config) MustGetByteSizeSlice(key string) []ByteSize {
+	return MustGet[[]ByteSize](config, key)
+}
+
+// GetByteSizeOr gets a byte size from the loaded configuration, returning the default value if that fails
+func (config *Config) GetByteSizeOr(key string, defaultValue ByteSize) ByteSize {
+	return GetOr[ByteSize](config, key, defaultValue)
+}
+
+// MustGetByteSize gets a byte size from the loaded configuration, panicking if that fails
+func (config *Config) MustGetByteSize(key string) ByteSize {
+	return MustGet[ByteSize](config, key)
+}
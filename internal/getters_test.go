@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGetOr(t *testing.T) {
+	config := NewConfig()
+	config.Use(NewMapLoader(map[string]interface{}{
+		"db": map[string]interface{}{"port": 5432.0, "host": "localhost"},
+	}))
+
+	Convey("Returns the value when it exists", t, func() {
+		So(GetOr[int](config, "db:port", 1), ShouldEqual, 5432)
+		So(config.GetStringOr("db:host", "default"), ShouldEqual, "localhost")
+	})
+
+	Convey("Returns the default when the key doesn't exist", t, func() {
+		So(GetOr[int](config, "db:missing", 1), ShouldEqual, 1)
+		So(config.GetStringSliceOr("db:missing", []string{"a"}), ShouldResemble, []string{"a"})
+		So(config.GetBooleanOr("db:missing", true), ShouldBeTrue)
+	})
+
+	Convey("Returns the default when the value can't be converted", t, func() {
+		So(config.GetIntegerOr("db:host", 1), ShouldEqual, 1)
+		So(config.GetFloatSliceOr("db:host", []float64{1}), ShouldResemble, []float64{1})
+	})
+}
+
+func TestMustGet(t *testing.T) {
+	config := NewConfig()
+	config.Use(NewMapLoader(map[string]interface{}{
+		"db": map[string]interface{}{"port": 5432.0, "host": "localhost"},
+	}))
+
+	Convey("Returns the value when it exists", t, func() {
+		So(MustGet[int](config, "db:port"), ShouldEqual, 5432)
+		So(config.MustGetFloat("db:port"), ShouldEqual, 5432)
+		So(config.MustGetMap("db"), ShouldResemble, map[string]interface{}{"port": 5432.0, "host": "localhost"})
+	})
+
+	Convey("Panics with the full key when the key doesn't exist", t, func() {
+		err := recoverError(func() { config.MustGetString("db:missing") })
+		So(err.Error(), ShouldEqual, "failed to get key 'db:missing': key 'missing' was not found")
+	})
+
+	Convey("Panics with the full key and the type found when the value can't be converted", t, func() {
+		err := recoverError(func() { config.MustGetInteger("db:host") })
+		So(err.Error(), ShouldEqual, "key 'db:host' contains string value, which can't be read as int: not a number")
+
+		conversionError := &ConversionError{}
+		So(errors.As(err, &conversionError), ShouldBeTrue)
+	})
+
+	Convey("Doesn't include the value when the value can't be converted", t, func() {
+		secretConfig := NewConfig()
+		secretConfig.Use(NewMapLoader(map[string]interface{}{"db": map[string]interface{}{"password": "hunter2"}}))
+
+		err := recoverError(func() { secretConfig.MustGetInteger("db:password") })
+		So(err.Error(), ShouldEqual, "key 'db:password' contains string value, which can't be read as int: not a number")
+		So(err.Error(), ShouldNotContainSubstring, "hunter2")
+	})
+}
+
+// recoverError calls the supplied function, returning the error it panicked with
+func recoverError(function func()) (err error) {
+	defer func() {
+		err = recover().(error)
+	}()
+	function()
+	return nil
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"encoding"
+	"math"
+	"math/big"
+	"net/url"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// DecodeHook defines a function that converts a configuration value before it's copied to a structure field. It's
+// called with the type of the value, the type of the field and the value itself, and should return the value as it
+// is if it doesn't handle the conversion
+type DecodeHook func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error)
+
+var (
+	urlType             = reflect.TypeOf(url.URL{})
+	regexpType          = reflect.TypeOf(regexp.Regexp{})
+	locationType        = reflect.TypeOf(time.Location{})
+	bigIntType          = reflect.TypeOf(big.Int{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// decodeHook combines the supplied custom hooks with the built in ones. Custom hooks run first, so they can replace the
+// built in conversions
+func decodeHook(hooks []DecodeHook) mapstructure.DecodeHookFunc {
+	composed := make([]mapstructure.DecodeHookFunc, 0, len(hooks)+2)
+	for _, hook := range hooks {
+		composed = append(composed, mapstructure.DecodeHookFuncType(hook))
+	}
+	composed = append(composed, coerceHook, typeHook)
+	return mapstructure.ComposeDecodeHookFunc(composed...)
+}
+
+// typeHook is a decode hook that converts strings into URLs, regular expressions, locations, big integers and any
+// type that implements encoding.TextUnmarshaler (e.g. net.IP). Pointers to these types are handled as well, since
+// mapstructure calls the hook again with the type they point to
+func typeHook(_ reflect.Type, target reflect.Type, data interface{}) (interface{}, error) {
+	if target == bigIntType {
+		return bigInt(data)
+	}
+
+	text, isString := data.(string)
+	if !isString {
+		return data, nil
+	}
+
+	switch target {
+	case urlType:
+		parsed, err := url.Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return *parsed, nil
+	case regexpType:
+		expression, err := regexp.Compile(text)
+		if err != nil {
+			return nil, err
+		}
+		return *expression, nil
+	case locationType:
+		location, err := time.LoadLocation(text)
+		if err != nil {
+			return nil, err
+		}
+		return *location, nil
+	}
+
+	if target.Kind() != reflect.Pointer && reflect.PointerTo(target).Implements(textUnmarshalerType) {
+		value := reflect.New(target)
+		err := value.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+		if err != nil {
+			return nil, err
+		}
+		return value.Elem().Interface(), nil
+	}
+
+	return data, nil
+}
+
+// bigInt converts a string or number into a big integer, failing for numbers with a fractional part
+func bigInt(data interface{}) (interface{}, error) {
+	result := big.Int{}
+
+	value := reflect.ValueOf(data)
+	switch value.Kind() {
+	case reflect.String:
+		_, valid := result.SetString(value.String(), 0)
+		if !valid {
+			return nil, conversionError(value, bigIntType, "not an integer")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result.SetInt64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		result.SetUint64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(value.Float()) || math.IsInf(value.Float(), 0) {
+			return nil, conversionError(value, bigIntType, "not an integer")
+		}
+		_, accuracy := big.NewFloat(value.Float()).Int(&result)
+		if accuracy != big.Exact {
+			return nil, conversionError(value, bigIntType, "value would lose precision")
+		}
+	default:
+		return data, nil
+	}
+
+	return result, nil
+}
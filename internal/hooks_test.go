@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type hooksLevel int
+
+func (level *hooksLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*level = 0
+	case "info":
+		*level = 1
+	default:
+		return fmt.Errorf("unknown level '%s'", text)
+	}
+	return nil
+}
+
+type hooksStructure struct {
+	Address  net.IP
+	Upstream *url.URL
+	Callback url.URL
+	Pattern  *regexp.Regexp
+	Zone     *time.Location
+	Started  time.Time
+	Timeout  time.Duration
+	Big      *big.Int
+	Count    big.Int
+	Level    hooksLevel
+	Levels   []hooksLevel
+}
+
+func TestDecodeHooks(t *testing.T) {
+
+	Convey("Decodes common types from strings and numbers", t, func() {
+		structure := hooksStructure{}
+		err := decode(map[string]interface{}{
+			"Address":  "10.0.0.1",
+			"Upstream": "https://upstream:8080/path",
+			"Callback": "http://localhost/callback",
+			"Pattern":  "^[a-z]+$",
+			"Zone":     "UTC",
+			"Started":  "2024-01-02T03:04:05Z",
+			"Timeout":  30,
+			"Big":      "123456789012345678901234567890",
+			"Count":    float64(42),
+			"Level":    "info",
+			"Levels":   []interface{}{"debug", "info"},
+		}, &structure)
+
+		So(err, ShouldBeNil)
+		So(structure.Address.Equal(net.ParseIP("10.0.0.1")), ShouldBeTrue)
+		So(structure.Upstream.Host, ShouldEqual, "upstream:8080")
+		So(structure.Callback.Path, ShouldEqual, "/callback")
+		So(structure.Pattern.MatchString("abc"), ShouldBeTrue)
+		So(structure.Pattern.MatchString("ABC"), ShouldBeFalse)
+		So(structure.Zone.String(), ShouldEqual, "UTC")
+		So(structure.Started.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)), ShouldBeTrue)
+		So(structure.Timeout, ShouldEqual, 30*time.Second)
+		So(structure.Big.String(), ShouldEqual, "123456789012345678901234567890")
+		So(structure.Count.Int64(), ShouldEqual, 42)
+		So(structure.Level, ShouldEqual, hooksLevel(1))
+		So(structure.Levels, ShouldResemble, []hooksLevel{0, 1})
+	})
+
+	Convey("Returns errors for values that can't be converted", t, func() {
+		invalid := map[string]interface{}{
+			"Address": "not an address",
+			"Pattern": "[",
+			"Zone":    "Nowhere/Special",
+			"Big":     "many",
+			"Count":   1.5,
+			"Level":   "verbose",
+		}
+		for key, value := range invalid {
+			err := decode(map[string]interface{}{key: value}, &hooksStructure{})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, key)
+		}
+	})
+}
+
+func TestWithDecodeHooks(t *testing.T) {
+	upperCase := func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		text, isString := data.(string)
+		if !isString || to.Kind() != reflect.String {
+			return data, nil
+		}
+		return strings.ToUpper(text), nil
+	}
+	level := func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if to != reflect.TypeOf(hooksLevel(0)) || data != "trace" {
+			return data, nil
+		}
+		return -1, nil
+	}
+
+	Convey("Runs custom hooks before the built in ones", t, func() {
+		config := NewConfig(WithDecodeHooks(upperCase), WithDecodeHooks(level))
+		config.Use(NewMapLoader(map[string]interface{}{"name": "service", "level": "trace", "levels": []interface{}{"info"}}))
+
+		structure := struct {
+			Name   string
+			Level  hooksLevel
+			Levels []hooksLevel
+		}{}
+		So(config.ToStructure(&structure), ShouldBeNil)
+		So(structure.Name, ShouldEqual, "SERVICE")
+		So(structure.Level, ShouldEqual, hooksLevel(-1))
+		So(structure.Levels, ShouldResemble, []hooksLevel{1})
+	})
+
+	Convey("Returns errors from custom hooks", t, func() {
+		failing := func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+			if data == "fail" {
+				return nil, fmt.Errorf("failed")
+			}
+			return data, nil
+		}
+
+		config := NewConfig(WithDecodeHooks(failing))
+		config.Use(NewMapLoader(map[string]interface{}{"name": "fail"}))
+		So(config.ToStructure(&struct{ Name string }{}), ShouldNotBeNil)
+	})
+
+	Convey("Passes the hooks on to sub configs", t, func() {
+		config := NewConfig(WithDecodeHooks(upperCase))
+		config.Use(NewMapLoader(map[string]interface{}{"db": map[string]interface{}{"name": "main"}}))
+
+		subConfig, err := config.GetSubConfig("db")
+		So(err, ShouldBeNil)
+
+		structure := struct{ Name string }{}
+		So(subConfig.ToStructure(&structure), ShouldBeNil)
+		So(structure.Name, ShouldEqual, "MAIN")
+	})
+}
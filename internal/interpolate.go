@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// environmentPrefix defines the prefix of references to environment variables, e.g. `${env:HOME}`
+const environmentPrefix = "env:"
+
+// InterpolationError describes a reference in a configuration value that couldn't be resolved
+type InterpolationError struct {
+	Key       string
+	Reference string
+	Err       error
+}
+
+// Error formats the interpolation error, including the key containing the reference
+func (err *InterpolationError) Error() string {
+	return fmt.Sprintf("failed to interpolate '${%s}' in key '%s': %v", err.Reference, err.Key, err.Err)
+}
+
+// Unwrap returns the underlying interpolation error
+func (err *InterpolationError) Unwrap() error {
+	return err.Err
+}
+
+// InterpolationErrors defines a collection of interpolation failures
+type InterpolationErrors []*InterpolationError
+
+// Error formats all the interpolation errors into a single message
+func (errs InterpolationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// interpolator resolves the references in the values of a configuration map
+type interpolator struct {
+	values    map[string]interface{}
+	delimiter string
+	fold      bool
+	secrets   map[string]SecretResolver
+	resolved  map[string]interface{}
+	resolving []string
+	reported  map[string]bool
+	errors    InterpolationErrors
+}
+
+// interpolate returns a copy of the supplied map with every reference in its string values resolved. References that
+// can't be resolved are left as they are, and are described by the returned errors
+func interpolate(values map[string]interface{}, configOptions options) (map[string]interface{}, InterpolationErrors) {
+	resolver := &interpolator{
+		values:    values,
+		delimiter: configOptions.delimiter,
+		fold:      configOptions.caseInsensitive,
+		secrets:   configOptions.secretResolvers,
+		resolved:  map[string]interface{}{},
+		reported:  map[string]bool{},
+	}
+
+	interpolated, _ := resolver.key([]string{}).(map[string]interface{})
+	return interpolated, resolver.errors
+}
+
+// key resolves the references in the value at the supplied keys, caching the result
+func (resolver *interpolator) key(keys []string) interface{} {
+	name := joinKey(keys, resolver.delimiter)
+	id := resolver.id(name)
+	resolved, isResolved := resolver.resolved[id]
+	if isResolved {
+		return resolved
+	}
+
+	resolver.resolving = append(resolver.resolving, name)
+	defer func() {
+		resolver.resolving = resolver.resolving[:len(resolver.resolving)-1]
+	}()
+
+	value, _ := getPath(resolver.values, keys, resolver.fold)
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		// Go through the keys in order, so the same errors are reported every time
+		names := make([]string, 0, len(typedValue))
+		for key := range typedValue {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+
+		interpolated := make(map[string]interface{}, len(typedValue))
+		for _, key := range names {
+			interpolated[key] = resolver.key(append(keys[:len(keys):len(keys)], key))
+		}
+		resolved = interpolated
+	case []interface{}:
+		interpolated := make([]interface{}, len(typedValue))
+		for i := range typedValue {
+			interpolated[i] = resolver.key(append(keys[:len(keys):len(keys)], strconv.Itoa(i)))
+		}
+		resolved = interpolated
+	case string:
+		resolved = resolver.string(typedValue, name)
+	default:
+		resolved = value
+	}
+
+	resolver.resolved[id] = resolved
+	return resolved
+}
+
+// string resolves the references in a string value. A string that consists of a single reference takes the referenced
+// value as it is, so that references to numbers, maps and slices keep their type
+func (resolver *interpolator) string(value string, key string) interface{} {
+	if !strings.Contains(value, "${") {
+		return value
+	}
+
+	builder := strings.Builder{}
+	for i := 0; i < len(value); {
+		switch {
+
+		// Escaped references are written as they are, without the extra dollar sign. Escaped secret references keep it,
+		// so that the secret resolvers know to leave them alone too
+		case strings.HasPrefix(value[i:], "$${"):
+			end := referenceEnd(value, i+3)
+			if end >= 0 && resolver.isSecret(value[i+3:end]) {
+				builder.WriteString(value[i : end+1])
+				i = end + 1
+				continue
+			}
+			builder.WriteString("${")
+			i += 3
+
+		case strings.HasPrefix(value[i:], "${"):
+			end := referenceEnd(value, i+2)
+			if end < 0 {
+				builder.WriteString(value[i:])
+				i = len(value)
+				continue
+			}
+
+			// Secret references are left for the secret resolvers, which resolve them when they're read
+			reference := value[i+2 : end]
+			if resolver.isSecret(reference) {
+				builder.WriteString(value[i : end+1])
+				i = end + 1
+				continue
+			}
+
+			resolved, err := resolver.reference(reference, key)
+			if err != nil {
+				resolver.report(key, reference, err)
+				builder.WriteString(value[i : end+1])
+			} else if i == 0 && end == len(value)-1 {
+				return copyValue(resolved)
+			} else {
+				builder.WriteString(fmt.Sprint(resolved))
+			}
+			i = end + 1
+
+		default:
+			builder.WriteByte(value[i])
+			i++
+		}
+	}
+
+	return builder.String()
+}
+
+// isSecret determines if a reference uses the scheme of a registered secret resolver
+func (resolver *interpolator) isSecret(reference string) bool {
+	scheme, _, _ := strings.Cut(reference, ":")
+	_, isSecret := resolver.secrets[scheme]
+	return isSecret
+}
+
+// reference resolves a single reference: the value of another key, an environment variable (`env:NAME`), or a
+// reference with a default (`name:-default`). A reference with a single segment that isn't a key is looked up in the
+// environment as well
+func (resolver *interpolator) reference(reference string, key string) (interface{}, error) {
+	name, defaultValue, hasDefault := strings.Cut(reference, ":-")
+
+	// Environment variables
+	if strings.HasPrefix(name, environmentPrefix) {
+		value, found := os.LookupEnv(strings.TrimPrefix(name, environmentPrefix))
+		if found {
+			return value, nil
+		}
+		if hasDefault {
+			return resolver.string(defaultValue, key), nil
+		}
+		return nil, fmt.Errorf("environment variable '%s' is not set", strings.TrimPrefix(name, environmentPrefix))
+	}
+
+	// Other keys, which are resolved first so that references can be chained
+	keys := splitKey(name, resolver.delimiter)
+	_, err := getPath(resolver.values, keys, resolver.fold)
+	if err == nil {
+		referenced := joinKey(keys, resolver.delimiter)
+		for i, resolving := range resolver.resolving {
+			id := resolver.id(resolving)
+			if id == resolver.id(referenced) || strings.HasPrefix(id, resolver.id(referenced)+resolver.delimiter) {
+				cycle := append(resolver.resolving[i:len(resolver.resolving):len(resolver.resolving)], referenced)
+				return nil, fmt.Errorf("reference cycle %s", strings.Join(cycle, " -> "))
+			}
+		}
+		return resolver.key(keys), nil
+	}
+
+	if len(keys) == 1 {
+		value, found := os.LookupEnv(name)
+		if found {
+			return value, nil
+		}
+	}
+
+	if hasDefault {
+		return resolver.string(defaultValue, key), nil
+	}
+	return nil, fmt.Errorf("key '%s' was not found", name)
+}
+
+// id returns the identifier of a key in the cache, which ignores case if keys are case-insensitive
+func (resolver *interpolator) id(key string) string {
+	if resolver.fold {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+// report records an interpolation error, once per key and reference
+func (resolver *interpolator) report(key string, reference string, err error) {
+	id := key + "\x00" + reference
+	if resolver.reported[id] {
+		return
+	}
+	resolver.reported[id] = true
+
+	resolver.errors = append(resolver.errors, &InterpolationError{
+		Key:       key,
+		Reference: reference,
+		Err:       err,
+	})
+}
+
+// referenceEnd returns the index of the brace that closes the reference starting at the supplied index, allowing
+// references to be nested in defaults. Returns -1 if the reference isn't closed
+func referenceEnd(value string, start int) int {
+	depth := 1
+	for i := start; i < len(value); i++ {
+		switch {
+		case strings.HasPrefix(value[i:], "${"):
+			depth++
+			i++
+		case value[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("GCONF_INTERPOLATE_TEST_HOST", "env-host")
+
+	Convey("Resolves references to other keys", t, func() {
+		values, errs := interpolate(map[string]interface{}{
+			"server": map[string]interface{}{"host": "localhost", "port": 8080},
+			"url":    "http://${server:host}:${server:port}/",
+		}, defaultOptions())
+		So(values["url"], ShouldEqual, "http://localhost:8080/")
+		So(errs, ShouldBeEmpty)
+	})
+
+	Convey("Keeps the type of values referenced by a whole string", t, func() {
+		values, errs := interpolate(map[string]interface{}{
+			"port":    8080,
+			"copy":    "${port}",
+			"servers": []interface{}{"a", "b"},
+			"backup":  "${servers}",
+		}, defaultOptions())
+		So(values["copy"], ShouldEqual, 8080)
+		So(values["backup"], ShouldResemble, []interface{}{"a", "b"})
+		So(errs, ShouldBeEmpty)
+	})
+
+	Convey("Resolves chained references, inside maps and slices", t, func() {
+		values, errs := interpolate(map[string]interface{}{
+			"a":     "${b}",
+			"b":     "${c:d}",
+			"c":     map[string]interface{}{"d": "value"},
+			"items": []interface{}{"${a}", map[string]interface{}{"name": "${c:d}!"}},
+		}, defaultOptions())
+		So(values["a"], ShouldEqual, "value")
+		So(values["items"], ShouldResemble, []interface{}{"value", map[string]interface{}{"name": "value!"}})
+		So(errs, ShouldBeEmpty)
+	})
+
+	Convey("Resolves environment variables", t, func() {
+		values, errs := interpolate(map[string]interface{}{
+			"explicit": "${env:GCONF_INTERPOLATE_TEST_HOST}",
+			"implicit": "${GCONF_INTERPOLATE_TEST_HOST}",
+		}, defaultOptions())
+		So(values["explicit"], ShouldEqual, "env-host")
+		So(values["implicit"], ShouldEqual, "env-host")
+		So(errs, ShouldBeEmpty)
+	})
+
+	Convey("Prefers keys to environment variables", t, func() {
+		values, _ := interpolate(map[string]interface{}{
+			"GCONF_INTERPOLATE_TEST_HOST": "key-host",
+			"host":                        "${GCONF_INTERPOLATE_TEST_HOST}",
+		}, defaultOptions())
+		So(values["host"], ShouldEqual, "key-host")
+	})
+
+	Convey("Uses defaults for missing references", t, func() {
+		values, errs := interpolate(map[string]interface{}{
+			"fallback": "fallback",
+			"port":     "${GCONF_INTERPOLATE_TEST_MISSING:-8080}",
+			"host":     "${env:GCONF_INTERPOLATE_TEST_MISSING:-${fallback}}",
+			"set":      "${GCONF_INTERPOLATE_TEST_HOST:-unused}",
+		}, defaultOptions())
+		So(values["port"], ShouldEqual, "8080")
+		So(values["host"], ShouldEqual, "fallback")
+		So(values["set"], ShouldEqual, "env-host")
+		So(errs, ShouldBeEmpty)
+	})
+
+	Convey("Writes escaped references as they are", t, func() {
+		values, errs := interpolate(map[string]interface{}{
+			"template": "$${name} costs $5 ${missing",
+		}, defaultOptions())
+		So(values["template"], ShouldEqual, "${name} costs $5 ${missing")
+		So(errs, ShouldBeEmpty)
+	})
+
+	Convey("Matches references case-insensitively if keys are", t, func() {
+		configOptions := defaultOptions()
+		configOptions.caseInsensitive = true
+		values, errs := interpolate(map[string]interface{}{
+			"Server": map[string]interface{}{"Host": "localhost"},
+			"url":    "${server:host}",
+		}, configOptions)
+		So(values["url"], ShouldEqual, "localhost")
+		So(errs, ShouldBeEmpty)
+	})
+
+	Convey("Reports missing references, leaving them in place", t, func() {
+		values, errs := interpolate(map[string]interface{}{
+			"url": "http://${server:host}/",
+		}, defaultOptions())
+		So(values["url"], ShouldEqual, "http://${server:host}/")
+		So(errs, ShouldHaveLength, 1)
+		So(errs[0].Key, ShouldEqual, "url")
+		So(errs[0].Reference, ShouldEqual, "server:host")
+		So(errs.Error(), ShouldEqual, "failed to interpolate '${server:host}' in key 'url': key 'server:host' was not found")
+	})
+
+	Convey("Reports missing environment variables", t, func() {
+		_, errs := interpolate(map[string]interface{}{
+			"host": "${env:GCONF_INTERPOLATE_TEST_MISSING}",
+		}, defaultOptions())
+		So(errs.Error(), ShouldEqual, "failed to interpolate '${env:GCONF_INTERPOLATE_TEST_MISSING}' in key 'host': environment variable 'GCONF_INTERPOLATE_TEST_MISSING' is not set")
+	})
+
+	Convey("Reports reference cycles", t, func() {
+		_, errs := interpolate(map[string]interface{}{
+			"a": "${b}",
+			"b": "${c}",
+			"c": "${a}",
+		}, defaultOptions())
+		So(errs, ShouldHaveLength, 1)
+		So(errs[0].Error(), ShouldEqual, "failed to interpolate '${a}' in key 'c': reference cycle a -> b -> c -> a")
+	})
+
+	Convey("Reports references to a key's own parent", t, func() {
+		_, errs := interpolate(map[string]interface{}{
+			"server": map[string]interface{}{"copy": "${server}"},
+		}, defaultOptions())
+		So(errs, ShouldHaveLength, 1)
+		So(errs[0].Error(), ShouldEqual, "failed to interpolate '${server}' in key 'server:copy': reference cycle server -> server:copy -> server")
+	})
+}
+
+func TestConfigInterpolation(t *testing.T) {
+
+	Convey("Doesn't interpolate unless enabled", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"a": "${b}", "b": "value"}))
+		So(config.Map()["a"], ShouldEqual, "${b}")
+	})
+
+	Convey("Interpolates across loaders", t, func() {
+		config := NewConfig(WithInterpolation())
+		config.Use(NewMapLoader(map[string]interface{}{"url": "http://${host}/"}))
+		So(config.Map()["url"], ShouldEqual, "http://${host}/")
+		So(config.Validate(), ShouldHaveSameTypeAs, InterpolationErrors{})
+
+		config.Use(NewMapLoader(map[string]interface{}{"host": "localhost"}))
+		value, err := config.GetString("url")
+		So(value, ShouldEqual, "http://localhost/")
+		So(err, ShouldBeNil)
+		So(config.Validate(), ShouldBeNil)
+
+		source, err := config.Source("url")
+		So(source, ShouldResemble, Source{Kind: "map", Index: 0})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Interpolates values written with Set, and values that reference them", t, func() {
+		config := NewConfig(WithInterpolation())
+		config.Use(NewMapLoader(map[string]interface{}{"url": "http://${host:-localhost}/"}))
+		So(config.Map()["url"], ShouldEqual, "http://localhost/")
+
+		So(config.Set("host", "example.com"), ShouldBeNil)
+		So(config.Set("greeting", "hello from ${host}"), ShouldBeNil)
+		So(config.Map()["url"], ShouldEqual, "http://example.com/")
+		So(config.Map()["greeting"], ShouldEqual, "hello from example.com")
+	})
+
+	Convey("Doesn't interpolate sub configs twice", t, func() {
+		config := NewConfig(WithInterpolation())
+		config.Use(NewMapLoader(map[string]interface{}{
+			"templates": map[string]interface{}{"name": "$${name}"},
+		}))
+
+		subConfig, err := config.GetSubConfig("templates")
+		So(err, ShouldBeNil)
+		So(subConfig.Map(), ShouldResemble, map[string]interface{}{"name": "${name}"})
+	})
+
+	Convey("Interpolates again on reload, keeping the existing values if a reference breaks", t, func() {
+		filePath := filepath.Join(t.TempDir(), "config.json")
+		So(os.WriteFile(filePath, []byte(`{"host": "first", "url": "http://${host}/"}`), 0o600), ShouldBeNil)
+
+		config := NewConfig(WithInterpolation())
+		config.Use(NewJSONFileLoader(filePath, false))
+		So(config.Map()["url"], ShouldEqual, "http://first/")
+
+		So(os.WriteFile(filePath, []byte(`{"host": "second", "url": "http://${host}/"}`), 0o600), ShouldBeNil)
+		So(config.Reload(), ShouldBeNil)
+		So(config.Map()["url"], ShouldEqual, "http://second/")
+
+		So(os.WriteFile(filePath, []byte(`{"url": "http://${host}/"}`), 0o600), ShouldBeNil)
+		err := config.Reload()
+		var interpolationErrors InterpolationErrors
+		So(errors.As(err, &interpolationErrors), ShouldBeTrue)
+		So(interpolationErrors[0].Key, ShouldEqual, "url")
+		So(config.Map()["url"], ShouldEqual, "http://second/")
+	})
+}
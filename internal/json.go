@@ -9,6 +9,7 @@ import (
 type JSONFileLoader struct {
 	filePath       string
 	parseDurations bool
+	optional       bool
 }
 
 // NewJSONFileLoader creates a new JSON file loader
@@ -19,9 +20,18 @@ func NewJSONFileLoader(filePath string, parseDurations bool) *JSONFileLoader {
 	}
 }
 
+// Optional marks the file as optional, so a missing file loads as an empty configuration
+func (loader *JSONFileLoader) Optional() *JSONFileLoader {
+	loader.optional = true
+	return loader
+}
+
 // Load loads a JSON file
 func (loader *JSONFileLoader) Load() (map[string]interface{}, error) {
 	file, err := os.ReadFile(loader.filePath)
+	if loader.optional && isNotExist(err) {
+		return map[string]interface{}{}, nil
+	}
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -15,6 +17,20 @@ func TestJSONFileLoad(t *testing.T) {
 		So(err, ShouldNotBeNil)
 	})
 
+	Convey("Returns an empty map when an optional file can't be found", t, func() {
+		result, err := NewJSONFileLoader("missing.json", false).Optional().Load()
+		So(result, ShouldResemble, map[string]interface{}{})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns an error when an optional file exists but can't be parsed", t, func() {
+		path := filepath.Join(t.TempDir(), "invalid.json")
+		So(os.WriteFile(path, []byte("{"), 0644), ShouldBeNil)
+
+		_, err := NewJSONFileLoader(path, false).Optional().Load()
+		So(err, ShouldNotBeNil)
+	})
+
 	Convey("Loads a JSON file", t, func() {
 		result, err := NewJSONFileLoader("../test/test.json", false).Load()
 		So(err, ShouldBeNil)
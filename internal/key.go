@@ -0,0 +1,61 @@
+package internal
+
+import "strings"
+
+// defaultDelimiter defines the key path delimiter used when none is configured
+const defaultDelimiter = ":"
+
+// splitKey splits the supplied key into its path segments on the delimiter. A segment can be wrapped in double quotes
+// to include the delimiter in it (e.g. `routes:"http://upstream":timeout`), and a backslash escapes the next character
+func splitKey(key string, delimiter string) []string {
+	keys := []string{}
+	segment := strings.Builder{}
+	segmentStart := true
+	quoted := false
+
+	for i := 0; i < len(key); {
+		switch {
+
+		// Backslashes escape the next character, both inside and outside of quotes
+		case key[i] == '\\' && i+1 < len(key):
+			segment.WriteByte(key[i+1])
+			i += 2
+
+		// Quotes at the start of a segment open a quoted segment, and the next quote closes it
+		case key[i] == '"' && (segmentStart || quoted):
+			quoted = !quoted
+			i++
+
+		// Unquoted delimiters end the segment
+		case !quoted && strings.HasPrefix(key[i:], delimiter):
+			keys = append(keys, segment.String())
+			segment.Reset()
+			segmentStart = true
+			i += len(delimiter)
+			continue
+
+		default:
+			segment.WriteByte(key[i])
+			i++
+		}
+		segmentStart = false
+	}
+
+	return append(keys, segment.String())
+}
+
+// joinKey joins the supplied path segments into a key, quoting any segments that contain the delimiter, quotes or
+// backslashes so that splitKey returns the same segments
+func joinKey(keys []string, delimiter string) string {
+	quotedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		if !strings.Contains(key, delimiter) && !strings.ContainsAny(key, `"\`) {
+			quotedKeys[i] = key
+			continue
+		}
+
+		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key)
+		quotedKeys[i] = `"` + escaped + `"`
+	}
+	return strings.Join(quotedKeys, delimiter)
+}
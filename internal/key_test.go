@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSplitKey(t *testing.T) {
+
+	Convey("Splits a key on the delimiter", t, func() {
+		So(splitKey("a:b:c", ":"), ShouldResemble, []string{"a", "b", "c"})
+		So(splitKey("a.b.c", "."), ShouldResemble, []string{"a", "b", "c"})
+		So(splitKey("a::b", "::"), ShouldResemble, []string{"a", "b"})
+	})
+
+	Convey("Returns a single segment when the key doesn't contain the delimiter", t, func() {
+		So(splitKey("a:b", "."), ShouldResemble, []string{"a:b"})
+		So(splitKey("", ":"), ShouldResemble, []string{""})
+	})
+
+	Convey("Keeps delimiters inside quoted segments", t, func() {
+		So(splitKey(`routes:"http://upstream":timeout`, ":"), ShouldResemble, []string{"routes", "http://upstream", "timeout"})
+		So(splitKey(`"a.b"`, "."), ShouldResemble, []string{"a.b"})
+	})
+
+	Convey("Keeps escaped delimiters and quotes", t, func() {
+		So(splitKey(`a\:b:c`, ":"), ShouldResemble, []string{"a:b", "c"})
+		So(splitKey(`"a\"b":c`, ":"), ShouldResemble, []string{`a"b`, "c"})
+		So(splitKey(`a\\:b`, ":"), ShouldResemble, []string{`a\`, "b"})
+	})
+
+	Convey("Treats quotes in the middle of a segment literally", t, func() {
+		So(splitKey(`a"b:c`, ":"), ShouldResemble, []string{`a"b`, "c"})
+	})
+
+	Convey("Treats an unterminated quote as running to the end of the key", t, func() {
+		So(splitKey(`a:"b:c`, ":"), ShouldResemble, []string{"a", "b:c"})
+	})
+}
+
+func TestJoinKey(t *testing.T) {
+
+	Convey("Joins segments with the delimiter", t, func() {
+		So(joinKey([]string{"a", "b"}, ":"), ShouldEqual, "a:b")
+		So(joinKey([]string{"a", "b"}, "."), ShouldEqual, "a.b")
+	})
+
+	Convey("Quotes segments that contain the delimiter, quotes or backslashes", t, func() {
+		So(joinKey([]string{"routes", "http://upstream"}, ":"), ShouldEqual, `routes:"http://upstream"`)
+		So(joinKey([]string{`a"b`, `c\d`}, ":"), ShouldEqual, `"a\"b":"c\\d"`)
+	})
+
+	Convey("Produces keys that split back into the same segments", t, func() {
+		keys := []string{"routes", "a:b", `c"d`, `e\`, "f.g"}
+		So(splitKey(joinKey(keys, ":"), ":"), ShouldResemble, keys)
+		So(splitKey(joinKey(keys, "."), "."), ShouldResemble, keys)
+	})
+}
@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LoaderError describes a loader in the configuration loading chain that failed to load
+type LoaderError struct {
+	Index  int
+	Kind   string
+	Name   string
+	Loader Loader
+	Err    error
+}
+
+// Error formats the loader error, including the loader kind, position and the underlying cause
+func (err *LoaderError) Error() string {
+	if len(err.Name) == 0 {
+		return fmt.Sprintf("%s loader at position %d failed: %v", err.Kind, err.Index, err.Err)
+	}
+	return fmt.Sprintf("%s loader '%s' at position %d failed: %v", err.Kind, err.Name, err.Index, err.Err)
+}
+
+// Unwrap returns the underlying loader error
+func (err *LoaderError) Unwrap() error {
+	return err.Err
+}
+
+// LoaderErrors defines a collection of loader failures
+type LoaderErrors []*LoaderError
+
+// Error formats all the loader errors into a single message
+func (errs LoaderErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// newLoaderError wraps the supplied error with information about the loader that produced it
+func newLoaderError(index int, loader Loader, err error) *LoaderError {
+	kind, name := describeLoader(loader)
+	return &LoaderError{
+		Index:  index,
+		Kind:   kind,
+		Name:   name,
+		Loader: loader,
+		Err:    err,
+	}
+}
+
+// describeLoader returns the kind of the supplied loader and a name identifying it (a file path or prefix)
+func describeLoader(loader Loader) (string, string) {
+	switch typedLoader := loader.(type) {
+	case *ArgumentLoader:
+		return "arguments", typedLoader.prefix
+	case *EnvironmentLoader:
+		return "environment", typedLoader.prefix
+	case *JSONFileLoader:
+		return "JSON file", typedLoader.filePath
+	case *YAMLFileLoader:
+		return "YAML file", typedLoader.filePath
+	case *MapLoader:
+		return "map", ""
+	case *DefaultsLoader:
+		return "defaults", fmt.Sprint(typedLoader.structure)
+	case *OptionalLoader:
+		return describeLoader(typedLoader.loader)
+	case *DecryptingLoader:
+		return describeLoader(typedLoader.loader)
+	default:
+		return fmt.Sprintf("%T", loader), ""
+	}
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// failingLoader is a loader that always fails with the configured error
+type failingLoader struct {
+	err error
+}
+
+// Load returns the configured error
+func (loader *failingLoader) Load() (map[string]interface{}, error) {
+	return nil, loader.err
+}
+
+func TestLoaderError(t *testing.T) {
+	cause := errors.New("boom")
+
+	Convey("Includes the loader kind, name, position and cause in the message", t, func() {
+		err := newLoaderError(2, NewJSONFileLoader("config.json", false), cause)
+		So(err.Error(), ShouldEqual, "JSON file loader 'config.json' at position 2 failed: boom")
+	})
+
+	Convey("Omits the name when the loader doesn't have one", t, func() {
+		err := newLoaderError(0, NewMapLoader(nil), cause)
+		So(err.Error(), ShouldEqual, "map loader at position 0 failed: boom")
+	})
+
+	Convey("Unwraps to the underlying cause", t, func() {
+		err := newLoaderError(0, NewMapLoader(nil), cause)
+		So(errors.Is(err, cause), ShouldBeTrue)
+	})
+
+	Convey("Joins multiple loader errors", t, func() {
+		err := LoaderErrors{
+			newLoaderError(0, NewYAMLFileLoader("a.yaml", false), cause),
+			newLoaderError(1, NewEnvironmentLoader(false, "", "APP_"), cause),
+		}
+		So(err.Error(), ShouldEqual, "YAML file loader 'a.yaml' at position 0 failed: boom; environment loader 'APP_' at position 1 failed: boom")
+	})
+}
+
+func TestDescribeLoader(t *testing.T) {
+
+	Convey("Describes the built in loaders", t, func() {
+		kind, name := describeLoader(NewArgumentLoader("__", "app"))
+		So(kind, ShouldEqual, "arguments")
+		So(name, ShouldEqual, "app")
+
+		kind, name = describeLoader(NewYAMLFileLoader("config.yaml", false))
+		So(kind, ShouldEqual, "YAML file")
+		So(name, ShouldEqual, "config.yaml")
+	})
+
+	Convey("Falls back to the type name for custom loaders", t, func() {
+		kind, name := describeLoader(&failingLoader{})
+		So(kind, ShouldEqual, "*internal.failingLoader")
+		So(name, ShouldBeEmpty)
+	})
+}
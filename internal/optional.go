@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// OptionalLoader defines a loader that wraps another loader, treating a missing source as an empty configuration
+type OptionalLoader struct {
+	loader Loader
+}
+
+// NewOptionalLoader creates a new optional loader wrapping the supplied loader
+func NewOptionalLoader(loader Loader) *OptionalLoader {
+	return &OptionalLoader{
+		loader: loader,
+	}
+}
+
+// Load loads the wrapped loader, returning an empty map if its source doesn't exist
+func (loader *OptionalLoader) Load() (map[string]interface{}, error) {
+	loadedMap, _, err := loader.loadWithNames()
+	return loadedMap, err
+}
+
+// loadWithNames loads the wrapped loader along with the per-key names it records, returning an empty map if its source
+// doesn't exist
+func (loader *OptionalLoader) loadWithNames() (map[string]interface{}, map[string]interface{}, error) {
+	loadedMap, names, err := loadWithNames(loader.loader)
+	if isNotExist(err) {
+		return map[string]interface{}{}, nil, nil
+	}
+	return loadedMap, names, err
+}
+
+// isNotExist determines if the supplied error indicates that a configuration source doesn't exist
+func isNotExist(err error) bool {
+	return err != nil && errors.Is(err, fs.ErrNotExist)
+}
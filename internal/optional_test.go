@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOptionalLoad(t *testing.T) {
+
+	Convey("Returns the wrapped loader's map when it loads successfully", t, func() {
+		result, err := NewOptionalLoader(NewMapLoader(map[string]interface{}{"one": 1})).Load()
+		So(result, ShouldResemble, map[string]interface{}{"one": 1})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns an empty map when the wrapped loader's source doesn't exist", t, func() {
+		result, err := NewOptionalLoader(NewYAMLFileLoader("missing.yaml", false)).Load()
+		So(result, ShouldResemble, map[string]interface{}{})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns other errors from the wrapped loader", t, func() {
+		cause := errors.New("boom")
+		_, err := NewOptionalLoader(&failingLoader{err: cause}).Load()
+		So(err, ShouldEqual, cause)
+	})
+
+	Convey("Returns parse errors from the wrapped loader", t, func() {
+		path := filepath.Join(t.TempDir(), "invalid.json")
+		So(os.WriteFile(path, []byte("{"), 0644), ShouldBeNil)
+
+		_, err := NewOptionalLoader(NewJSONFileLoader(path, false)).Load()
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Is described as the wrapped loader", t, func() {
+		kind, name := describeLoader(NewOptionalLoader(NewJSONFileLoader("config.json", false)))
+		So(kind, ShouldEqual, "JSON file")
+		So(name, ShouldEqual, "config.json")
+	})
+}
+
+func TestIsNotExist(t *testing.T) {
+
+	Convey("Returns false for nil errors", t, func() {
+		So(isNotExist(nil), ShouldBeFalse)
+	})
+
+	Convey("Returns true for wrapped not found errors", t, func() {
+		_, err := os.ReadFile("missing")
+		So(isNotExist(newLoaderError(0, NewMapLoader(nil), err)), ShouldBeTrue)
+	})
+
+	Convey("Returns false for other errors", t, func() {
+		So(isNotExist(errors.New("boom")), ShouldBeFalse)
+	})
+}
@@ -0,0 +1,82 @@
+package internal
+
+// Option defines an option that changes the behaviour of a configuration
+type Option func(*options)
+
+// options defines the configurable behaviour of a configuration
+type options struct {
+	delimiter       string
+	caseInsensitive bool
+	schema          *Schema
+	decodeHooks     []DecodeHook
+	interpolate     bool
+	secretResolvers map[string]SecretResolver
+}
+
+// defaultOptions returns the options used when a configuration is created without any
+func defaultOptions() options {
+	return options{
+		delimiter: defaultDelimiter,
+	}
+}
+
+// WithDelimiter sets the delimiter used to separate the segments of a key path (`:` by default)
+func WithDelimiter(delimiter string) Option {
+	return func(configOptions *options) {
+		if len(delimiter) > 0 {
+			configOptions.delimiter = delimiter
+		}
+	}
+}
+
+// WithCaseInsensitiveKeys makes key lookups, merging and Set ignore the case of keys. Keys that only differ in case are
+// merged into one, keeping the case of the key from the highest priority loader
+func WithCaseInsensitiveKeys() Option {
+	return func(configOptions *options) {
+		configOptions.caseInsensitive = true
+	}
+}
+
+// WithSchema attaches a JSON Schema to the configuration. The configuration is validated against it by Validate, and
+// reloads that produce a configuration that doesn't match it are rejected
+func WithSchema(schema *Schema) Option {
+	return func(configOptions *options) {
+		configOptions.schema = schema
+	}
+}
+
+// WithDecodeHooks adds hooks that convert values before they're copied to structure fields by ToStructure. They run
+// in order, before the built in conversions
+func WithDecodeHooks(hooks ...DecodeHook) Option {
+	return func(configOptions *options) {
+		configOptions.decodeHooks = append(configOptions.decodeHooks[:len(configOptions.decodeHooks):len(configOptions.decodeHooks)], hooks...)
+	}
+}
+
+// WithInterpolation resolves references such as `${server:host}`, `${env:HOME}` and `${PORT:-8080}` in string values
+// whenever the configuration is loaded, reloaded or set. `$${` is written as a literal `${`
+func WithInterpolation() Option {
+	return func(configOptions *options) {
+		configOptions.interpolate = true
+	}
+}
+
+// WithSecretResolver resolves secret references with the supplied scheme, such as `vault://db/password` or
+// `${vault:db/password}`, with the supplied resolver. Secrets are resolved when they're read, and cached until the
+// configuration is reloaded
+func WithSecretResolver(scheme string, resolver SecretResolver) Option {
+	return func(configOptions *options) {
+		resolvers := make(map[string]SecretResolver, len(configOptions.secretResolvers)+1)
+		for existingScheme, existingResolver := range configOptions.secretResolvers {
+			resolvers[existingScheme] = existingResolver
+		}
+		resolvers[scheme] = resolver
+		configOptions.secretResolvers = resolvers
+	}
+}
+
+// WithFileSecrets resolves secret references like `file:///run/secrets/db_pass` and `${file:/run/secrets/db_pass}` with
+// the contents of the file
+func WithFileSecrets() Option {
+	return WithSecretResolver(fileSecretScheme, ReadSecretFile)
+}
@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWithDelimiter(t *testing.T) {
+	values := map[string]interface{}{
+		"routes": map[string]interface{}{
+			"http://upstream": map[string]interface{}{"timeout": "5s"},
+		},
+		"hosts": map[string]interface{}{
+			"api.example.com": map[string]interface{}{"timeout": "10s"},
+		},
+		"db": map[string]interface{}{"host": "localhost"},
+	}
+
+	Convey("Uses a colon by default", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(values))
+
+		value, err := config.GetString(`routes:"http://upstream":timeout`)
+		So(value, ShouldEqual, "5s")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Uses the configured delimiter for every key", t, func() {
+		config := NewConfig(WithDelimiter("."))
+		config.Use(NewMapLoader(values))
+
+		value, err := config.GetString("routes.http://upstream.timeout")
+		So(value, ShouldEqual, "5s")
+		So(err, ShouldBeNil)
+
+		value, err = config.GetString("hosts.api.example.com.timeout")
+		So(value, ShouldBeEmpty)
+		So(err, ShouldNotBeNil)
+
+		value, err = config.GetString(`hosts."api.example.com".timeout`)
+		So(value, ShouldEqual, "10s")
+		So(err, ShouldBeNil)
+
+		So(config.Set("db.port", 5432), ShouldBeNil)
+		port, err := config.GetInteger("db.port")
+		So(port, ShouldEqual, 5432)
+		So(err, ShouldBeNil)
+
+		source, err := config.Source("db.port")
+		So(source, ShouldResemble, setSource)
+		So(err, ShouldBeNil)
+
+		explanation, err := config.Explain("db.host")
+		So(explanation.Value, ShouldEqual, "localhost")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Passes the delimiter on to sub configs and snapshots", t, func() {
+		config := NewConfig(WithDelimiter("."))
+		config.Use(NewMapLoader(values))
+
+		subConfig, err := config.GetSubConfig("routes")
+		So(err, ShouldBeNil)
+		value, err := subConfig.GetString(`"http://upstream".timeout`)
+		So(value, ShouldEqual, "5s")
+		So(err, ShouldBeNil)
+
+		value, err = config.Snapshot().GetString("db.host")
+		So(value, ShouldEqual, "localhost")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Uses the configured delimiter for prefix subscriptions", t, func() {
+		config := NewConfig(WithDelimiter("."))
+		config.Use(NewMapLoader(values))
+
+		var changes []change
+		config.OnChange("db.*", recordChanges(&changes))
+		So(config.Set("db.port", 5432), ShouldBeNil)
+		So(changes, ShouldHaveLength, 1)
+	})
+
+	Convey("Ignores an empty delimiter", t, func() {
+		config := NewConfig(WithDelimiter(""))
+		config.Use(NewMapLoader(values))
+
+		value, err := config.GetString("db:host")
+		So(value, ShouldEqual, "localhost")
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestWithCaseInsensitiveKeys(t *testing.T) {
+	load := func(configOptions ...Option) *Config {
+		config := NewConfig(configOptions...)
+		config.Use(NewMapLoader(map[string]interface{}{"dbHost": "arguments", "db": map[string]interface{}{"Port": 1}}))
+		config.Use(NewMapLoader(map[string]interface{}{"DBHOST": "environment", "DB": map[string]interface{}{"user": "admin"}}))
+		config.Use(NewMapLoader(map[string]interface{}{"dbhost": "file"}))
+		return config
+	}
+
+	Convey("Keeps keys that differ in case apart by default", t, func() {
+		config := load()
+		So(config.Map(), ShouldContainKey, "dbHost")
+		So(config.Map(), ShouldContainKey, "DBHOST")
+		So(config.Map(), ShouldContainKey, "dbhost")
+	})
+
+	Convey("Merges keys that differ in case, keeping the case of the winning key", t, func() {
+		config := load(WithCaseInsensitiveKeys())
+		So(config.Map(), ShouldResemble, map[string]interface{}{
+			"dbHost": "arguments",
+			"db":     map[string]interface{}{"Port": 1, "user": "admin"},
+		})
+	})
+
+	Convey("Ignores case when getting keys", t, func() {
+		config := load(WithCaseInsensitiveKeys())
+
+		value, err := config.GetString("DBHOST")
+		So(value, ShouldEqual, "arguments")
+		So(err, ShouldBeNil)
+
+		port, err := config.GetInteger("DB:port")
+		So(port, ShouldEqual, 1)
+		So(err, ShouldBeNil)
+
+		subConfig, err := config.GetSubConfig("Db")
+		So(err, ShouldBeNil)
+		user, err := subConfig.GetString("USER")
+		So(user, ShouldEqual, "admin")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Ignores case when setting keys", t, func() {
+		config := load(WithCaseInsensitiveKeys())
+
+		So(config.Set("DBHOST", "set"), ShouldNotBeNil)
+		So(config.Set("DB:PASSWORD", "secret"), ShouldBeNil)
+		So(config.Map()["db"], ShouldResemble, map[string]interface{}{"Port": 1, "user": "admin", "PASSWORD": "secret"})
+
+		source, err := config.Source("db:password")
+		So(source, ShouldResemble, setSource)
+		So(err, ShouldBeNil)
+
+		So(config.Reload(), ShouldBeNil)
+		So(config.Map()["db"], ShouldResemble, map[string]interface{}{"Port": 1, "user": "admin", "PASSWORD": "secret"})
+	})
+
+	Convey("Ignores case when explaining keys", t, func() {
+		config := load(WithCaseInsensitiveKeys())
+
+		explanation, err := config.Explain("DbHost")
+		So(err, ShouldBeNil)
+		So(explanation.Value, ShouldEqual, "arguments")
+		So(explanation.Source.Index, ShouldEqual, 0)
+		So(explanation.Shadowed, ShouldHaveLength, 2)
+	})
+
+	Convey("Ignores case when notifying subscribers", t, func() {
+		config := load(WithCaseInsensitiveKeys())
+
+		var changes []change
+		config.OnChange("DB:*", recordChanges(&changes))
+		So(config.Set("db:Password", "secret"), ShouldBeNil)
+		So(changes, ShouldHaveLength, 1)
+	})
+
+	Convey("Ignores case when copying to a structure", t, func() {
+		config := load(WithCaseInsensitiveKeys())
+
+		structure := struct {
+			DBHost string `mapstructure:"dbhost"`
+			DB     struct {
+				Port int
+				User string
+			}
+		}{}
+		So(config.ToStructure(&structure), ShouldBeNil)
+		So(structure.DBHost, ShouldEqual, "arguments")
+		So(structure.DB.Port, ShouldEqual, 1)
+		So(structure.DB.User, ShouldEqual, "admin")
+	})
+}
@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// DecodeReport describes the keys in a configuration that didn't map to any structure field, and the structure fields
+// that no loader set
+type DecodeReport struct {
+	Unused []UnusedKey
+	Unset  []string
+}
+
+// UnusedKey defines a configuration key that didn't map to any structure field, along with the source it came from
+type UnusedKey struct {
+	Key    string
+	Source *Source
+}
+
+// String formats the unused key along with its source
+func (key UnusedKey) String() string {
+	if key.Source == nil {
+		return fmt.Sprintf("'%s'", key.Key)
+	}
+	return fmt.Sprintf("'%s' from %s", key.Key, key.Source)
+}
+
+// UnusedKeysError describes the configuration keys that didn't map to any field when copying to a structure strictly
+type UnusedKeysError struct {
+	Keys []UnusedKey
+}
+
+// Error formats the unused keys error, listing every unused key and its source
+func (err *UnusedKeysError) Error() string {
+	keys := make([]string, len(err.Keys))
+	for i, key := range err.Keys {
+		keys[i] = key.String()
+	}
+	return fmt.Sprintf("configuration contains keys that don't map to any field: %s", strings.Join(keys, ", "))
+}
+
+// applyDecodeOptions applies the supplied decode options to the default options
+func applyDecodeOptions(options []DecodeOption) decodeOptions {
+	applied := decodeOptions{}
+	for _, option := range options {
+		option(&applied)
+	}
+	return applied
+}
+
+// newDecodeReport builds a report from the metadata recorded while decoding the value at the supplied keys. Fields
+// that were only filled with their default count as unset
+func newDecodeReport(metadata *mapstructure.Metadata, keys []string, currentState *state, configOptions options) *DecodeReport {
+	report := &DecodeReport{}
+
+	// Unused maps are listed key by key, so that every key can be traced back to its source
+	for _, name := range sortedNames(metadata.Unused) {
+		for _, unusedKeys := range leafKeys(currentState.values, fullKeys(keys, name)) {
+			unused := UnusedKey{Key: joinKey(unusedKeys, configOptions.delimiter)}
+			source, found := currentState.sourceOf(unusedKeys, configOptions.caseInsensitive)
+			if found {
+				unused.Source = &source
+			}
+			report.Unused = append(report.Unused, unused)
+		}
+	}
+
+	unset := append([]string{}, metadata.Unset...)
+	for _, name := range metadata.Keys {
+		_, err := getPath(currentState.values, fullKeys(keys, name), true)
+		if err != nil && !hasChildName(metadata.Keys, name) {
+			unset = append(unset, name)
+		}
+	}
+	for _, name := range sortedNames(unset) {
+		report.Unset = append(report.Unset, joinKey(fullKeys(keys, name), configOptions.delimiter))
+	}
+
+	return report
+}
+
+// fullKeys returns the full key path of a name recorded by mapstructure while decoding the value at the supplied keys
+func fullKeys(keys []string, name string) []string {
+	return append(keys[:len(keys):len(keys)], metadataKeys(name)...)
+}
+
+// leafKeys returns the keys of every value below the supplied key that isn't a map, in order
+func leafKeys(m map[string]interface{}, keys []string) [][]string {
+	value, _ := getPath(m, keys, true)
+	mapValue, isMap := value.(map[string]interface{})
+	if !isMap || len(mapValue) == 0 {
+		return [][]string{keys}
+	}
+
+	childKeys := make([]string, 0, len(mapValue))
+	for key := range mapValue {
+		childKeys = append(childKeys, key)
+	}
+	sort.Strings(childKeys)
+
+	var leaves [][]string
+	for _, key := range childKeys {
+		leaves = append(leaves, leafKeys(m, append(keys[:len(keys):len(keys)], key))...)
+	}
+	return leaves
+}
+
+// sortedNames returns a sorted copy of the supplied names, without duplicates
+func sortedNames(names []string) []string {
+	var sorted []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// hasChildName determines if any of the supplied mapstructure names is nested below the supplied name
+func hasChildName(names []string, name string) bool {
+	for _, other := range names {
+		if strings.HasPrefix(other, name+".") || strings.HasPrefix(other, name+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataKeys splits a name recorded by mapstructure (e.g. `servers[0].host`) into key path segments
+func metadataKeys(name string) []string {
+	keys := []string{}
+	segment := strings.Builder{}
+
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '.':
+			keys = append(keys, segment.String())
+			segment.Reset()
+		case '[':
+			end := strings.IndexByte(name[i:], ']')
+			if end < 0 {
+				segment.WriteString(name[i:])
+				i = len(name)
+				continue
+			}
+			if segment.Len() > 0 {
+				keys = append(keys, segment.String())
+				segment.Reset()
+			}
+			keys = append(keys, name[i+1:i+end])
+			i += end
+			if i+1 < len(name) && name[i+1] == '.' {
+				i++
+			}
+		default:
+			segment.WriteByte(name[i])
+		}
+	}
+
+	if segment.Len() > 0 {
+		keys = append(keys, segment.String())
+	}
+	return keys
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type reportServer struct {
+	Host string `mapstructure:"host"`
+	Port int    `mapstructure:"port" default:"80"`
+}
+
+type reportStructure struct {
+	Database reportServer   `mapstructure:"database"`
+	Servers  []reportServer `mapstructure:"servers"`
+	Name     string         `mapstructure:"name"`
+}
+
+func TestStrict(t *testing.T) {
+	t.Setenv("GCONF_REPORT_TEST_DATABSE__HOST", "typo")
+
+	load := func() *Config {
+		config := NewConfig()
+		config.Use(NewEnvironmentLoader(false, "__", "GCONF_REPORT_TEST_"))
+		config.Use(NewMapLoader(map[string]interface{}{
+			"database": map[string]interface{}{"host": "localhost", "user": "admin"},
+			"servers":  []interface{}{map[string]interface{}{"host": "a", "weight": 1}},
+		}))
+		return config
+	}
+
+	Convey("Ignores unused keys by default", t, func() {
+		So(load().ToStructure(&reportStructure{}), ShouldBeNil)
+	})
+
+	Convey("Fails on unused keys, naming their sources", t, func() {
+		err := load().ToStructure(&reportStructure{}, Strict())
+		So(err, ShouldHaveSameTypeAs, &UnusedKeysError{})
+		So(err.(*UnusedKeysError).Keys, ShouldResemble, []UnusedKey{
+			{Key: "DATABSE:HOST", Source: &Source{Kind: "environment", Name: "GCONF_REPORT_TEST_DATABSE__HOST", Index: 0}},
+			{Key: "database:user", Source: &Source{Kind: "map", Index: 1}},
+			{Key: "servers:0:weight", Source: &Source{Kind: "map", Index: 1}},
+		})
+		So(err.Error(), ShouldEqual, "configuration contains keys that don't map to any field: "+
+			"'DATABSE:HOST' from environment 'GCONF_REPORT_TEST_DATABSE__HOST' (position 0), "+
+			"'database:user' from map (position 1), 'servers:0:weight' from map (position 1)")
+	})
+
+	Convey("Succeeds when every key is used", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"name": "service"}))
+		So(config.ToStructure(&reportStructure{}, Strict()), ShouldBeNil)
+	})
+}
+
+func TestWithReport(t *testing.T) {
+
+	Convey("Reports unused keys and fields that no loader set", t, func() {
+		config := NewConfig(WithDelimiter("."))
+		config.Use(NewMapLoader(map[string]interface{}{
+			"database": map[string]interface{}{"host": "localhost", "user": "admin"},
+			"servers":  []interface{}{map[string]interface{}{"port": 1}},
+		}))
+
+		report := DecodeReport{}
+		So(config.ToStructure(&reportStructure{}, WithReport(&report)), ShouldBeNil)
+		So(report.Unused, ShouldResemble, []UnusedKey{{Key: "database.user", Source: &Source{Kind: "map", Index: 0}}})
+		So(report.Unset, ShouldResemble, []string{"database.port", "name", "servers.0.host"})
+	})
+
+	Convey("Reports an empty configuration", t, func() {
+		report := DecodeReport{}
+		So(NewConfig().ToStructure(&reportStructure{}, WithReport(&report)), ShouldBeNil)
+		So(report.Unused, ShouldBeEmpty)
+		So(report.Unset, ShouldResemble, []string{"database:host", "database:port", "name", "servers"})
+	})
+}
+
+func TestLeafKeys(t *testing.T) {
+	m := map[string]interface{}{
+		"a": map[string]interface{}{"c": 1, "b": map[string]interface{}{"d": 2}, "e": map[string]interface{}{}},
+		"f": []interface{}{1},
+	}
+
+	Convey("Returns the keys of every value below a map in order", t, func() {
+		So(leafKeys(m, []string{"a"}), ShouldResemble, [][]string{{"a", "b", "d"}, {"a", "c"}, {"a", "e"}})
+	})
+
+	Convey("Returns the key itself for other values", t, func() {
+		So(leafKeys(m, []string{"f"}), ShouldResemble, [][]string{{"f"}})
+		So(leafKeys(m, []string{"missing"}), ShouldResemble, [][]string{{"missing"}})
+	})
+}
+
+func TestMetadataKeys(t *testing.T) {
+
+	Convey("Splits mapstructure names into keys", t, func() {
+		So(metadataKeys("name"), ShouldResemble, []string{"name"})
+		So(metadataKeys("database.host"), ShouldResemble, []string{"database", "host"})
+		So(metadataKeys("servers[0].host"), ShouldResemble, []string{"servers", "0", "host"})
+		So(metadataKeys("routes[http://upstream].timeout"), ShouldResemble, []string{"routes", "http://upstream", "timeout"})
+		So(metadataKeys("[key]"), ShouldResemble, []string{"key"})
+		So(metadataKeys("matrix[0][1]"), ShouldResemble, []string{"matrix", "0", "1"})
+	})
+}
@@ -0,0 +1,221 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Schema defines a compiled JSON Schema (draft 2020-12 unless the schema declares another draft) that configurations
+// can be validated against
+type Schema struct {
+	schema *jsonschema.Schema
+}
+
+// ParseSchema compiles a JSON Schema from the supplied bytes. References to other schemas aren't loaded, so every
+// referenced schema has to be defined in the same document
+func ParseSchema(data []byte) (*Schema, error) {
+	return compileSchema("file:///schema.json", data, func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("can't load referenced schema '%s' from a schema that was parsed from bytes", url)
+	})
+}
+
+// LoadSchemaFile compiles a JSON Schema from the supplied file. References to other local files are loaded, but
+// remote references are not
+func LoadSchemaFile(filePath string) (*Schema, error) {
+	absolutePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(absolutePath)
+	if err != nil {
+		return nil, err
+	}
+
+	location := url.URL{Scheme: "file", Path: filepath.ToSlash(absolutePath)}
+	return compileSchema(location.String(), data, func(reference string) (io.ReadCloser, error) {
+		referenceURL, err := url.Parse(reference)
+		if err != nil || referenceURL.Scheme != "file" {
+			return nil, fmt.Errorf("can't load remote schema '%s'", reference)
+		}
+		return os.Open(filepath.FromSlash(referenceURL.Path))
+	})
+}
+
+// LoadSchemaFS compiles a JSON Schema from the supplied file in a file system (such as an embed.FS). References to
+// other files in the same file system are loaded, but remote references are not
+func LoadSchemaFS(fileSystem fs.FS, filePath string) (*Schema, error) {
+	data, err := fs.ReadFile(fileSystem, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	location := url.URL{Scheme: "file", Path: "/" + filePath}
+	return compileSchema(location.String(), data, func(reference string) (io.ReadCloser, error) {
+		referenceURL, err := url.Parse(reference)
+		if err != nil || referenceURL.Scheme != "file" {
+			return nil, fmt.Errorf("can't load remote schema '%s'", reference)
+		}
+		return fileSystem.Open(strings.TrimPrefix(referenceURL.Path, "/"))
+	})
+}
+
+// compileSchema compiles the schema at the supplied location, using the supplied function to load any schemas it
+// references
+func compileSchema(location string, data []byte, loadURL func(string) (io.ReadCloser, error)) (*Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true
+	compiler.LoadURL = loadURL
+
+	err := compiler.AddResource(location, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	schema, err := compiler.Compile(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema: %w", err)
+	}
+
+	return &Schema{schema: schema}, nil
+}
+
+// SchemaError describes a location in the configuration that doesn't match the JSON Schema
+type SchemaError struct {
+	Key     string
+	Pointer string
+	Source  *Source
+	Message string
+}
+
+// Error formats the schema error, including the key and the source of its value
+func (err *SchemaError) Error() string {
+	switch {
+	case len(err.Pointer) == 0:
+		return fmt.Sprintf("configuration: %s", err.Message)
+	case err.Source == nil:
+		return fmt.Sprintf("key '%s': %s", err.Key, err.Message)
+	default:
+		return fmt.Sprintf("key '%s' from %s: %s", err.Key, err.Source, err.Message)
+	}
+}
+
+// SchemaErrors defines a collection of schema violations
+type SchemaErrors []*SchemaError
+
+// Error formats all the schema errors into a single message
+func (errs SchemaErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate validates the loaded configuration against the schema it was created with, if any. References that
+// couldn't be interpolated are returned as InterpolationErrors first
+func (config *Config) Validate() error {
+	currentState := config.current()
+	if len(currentState.interpolation) > 0 {
+		return currentState.interpolation
+	}
+	if config.options.schema == nil {
+		return nil
+	}
+	return config.ValidateSchema(config.options.schema)
+}
+
+// ValidateSchema validates the loaded configuration against the supplied schema, returning SchemaErrors that lists
+// every violation
+func (config *Config) ValidateSchema(schema *Schema) error {
+	return validateSchema(schema, config.current(), config.options)
+}
+
+// validateSchema validates the values in the supplied state against the schema, mapping the location of each violation
+// to a key
+func validateSchema(schema *Schema, currentState *state, configOptions options) error {
+	document, err := schemaDocument(currentState.values)
+	if err != nil {
+		return err
+	}
+
+	err = schema.schema.Validate(document)
+	var validationError *jsonschema.ValidationError
+	if !errors.As(err, &validationError) {
+		return err
+	}
+
+	var errs SchemaErrors
+	for _, cause := range schemaCauses(validationError) {
+		keys := pointerKeys(cause.InstanceLocation)
+		schemaError := &SchemaError{
+			Key:     joinKey(keys, configOptions.delimiter),
+			Pointer: cause.InstanceLocation,
+			Message: cause.Message,
+		}
+		source, found := currentState.sourceOf(keys, configOptions.caseInsensitive)
+		if found && len(keys) > 0 {
+			schemaError.Source = &source
+		}
+		errs = append(errs, schemaError)
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Pointer < errs[j].Pointer
+	})
+	return errs
+}
+
+// schemaCauses returns the errors at the leaves of a validation error, which describe the individual violations
+func schemaCauses(validationError *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(validationError.Causes) == 0 {
+		return []*jsonschema.ValidationError{validationError}
+	}
+
+	var causes []*jsonschema.ValidationError
+	for _, cause := range validationError.Causes {
+		causes = append(causes, schemaCauses(cause)...)
+	}
+	return causes
+}
+
+// schemaDocument converts a configuration map into the JSON types the schema validates. Durations and times are
+// written as strings, the same way they're written in configuration files
+func schemaDocument(values map[string]interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(PrintableValue(values))
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+
+	var document interface{}
+	err = decoder.Decode(&document)
+	return document, err
+}
+
+// pointerKeys converts a JSON pointer into key path segments
+func pointerKeys(pointer string) []string {
+	if len(pointer) == 0 {
+		return []string{}
+	}
+
+	keys := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, key := range keys {
+		keys[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+	}
+	return keys
+}
@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["db"],
+	"properties": {
+		"db": {
+			"type": "object",
+			"required": ["host"],
+			"properties": {
+				"host": {"type": "string"},
+				"port": {"type": "integer", "minimum": 1}
+			}
+		},
+		"timeout": {"type": "string", "pattern": "^[0-9]+[a-z]+$"},
+		"routes": {
+			"type": "object",
+			"additionalProperties": {"type": "integer"}
+		},
+		"servers": {
+			"type": "array",
+			"items": {"type": "string", "format": "hostname"}
+		}
+	}
+}`
+
+func TestParseSchema(t *testing.T) {
+
+	Convey("Compiles a schema from bytes", t, func() {
+		schema, err := ParseSchema([]byte(testSchema))
+		So(schema, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns an error for an invalid schema", t, func() {
+		_, err := ParseSchema([]byte(`{"type": 5}`))
+		So(err, ShouldNotBeNil)
+
+		_, err = ParseSchema([]byte(`{`))
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Doesn't load remote references", t, func() {
+		_, err := ParseSchema([]byte(`{"$ref": "https://example.com/schema.json"}`))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestLoadSchemaFile(t *testing.T) {
+	directory := t.TempDir()
+	writeFile := func(name string, contents string) string {
+		filePath := filepath.Join(directory, name)
+		err := os.WriteFile(filePath, []byte(contents), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return filePath
+	}
+
+	port := writeFile("port.json", `{"type": "integer", "minimum": 1}`)
+	withReference := writeFile("config.json", `{"properties": {"port": {"$ref": "port.json"}}}`)
+	withRemoteReference := writeFile("remote.json", `{"properties": {"port": {"$ref": "https://example.com/port.json"}}}`)
+
+	Convey("Compiles a schema from a file", t, func() {
+		schema, err := LoadSchemaFile(port)
+		So(schema, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Loads references to local files", t, func() {
+		schema, err := LoadSchemaFile(withReference)
+		So(err, ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"port": 0}))
+		So(config.ValidateSchema(schema), ShouldNotBeNil)
+	})
+
+	Convey("Doesn't load remote references", t, func() {
+		_, err := LoadSchemaFile(withRemoteReference)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Returns an error when the file doesn't exist", t, func() {
+		_, err := LoadSchemaFile(filepath.Join(directory, "missing.json"))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestLoadSchemaFS(t *testing.T) {
+	fileSystem := fstest.MapFS{
+		"schemas/config.json": {Data: []byte(`{"properties": {"port": {"$ref": "port.json"}}}`)},
+		"schemas/port.json":   {Data: []byte(`{"type": "integer", "minimum": 1}`)},
+	}
+
+	Convey("Compiles a schema from a file system, loading references to other files in it", t, func() {
+		schema, err := LoadSchemaFS(fileSystem, "schemas/config.json")
+		So(err, ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"port": 0}))
+		err = config.ValidateSchema(schema)
+		So(err, ShouldNotBeNil)
+		So(err.(SchemaErrors)[0].Key, ShouldEqual, "port")
+	})
+
+	Convey("Returns an error when the file doesn't exist", t, func() {
+		_, err := LoadSchemaFS(fileSystem, "schemas/missing.json")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Accepts a configuration that matches the schema", t, func() {
+		config := NewConfig(WithSchema(schema))
+		config.Use(NewMapLoader(map[string]interface{}{
+			"db":      map[string]interface{}{"host": "localhost", "port": 5432},
+			"timeout": 30 * time.Second,
+			"servers": []interface{}{"a.example.com"},
+		}))
+		So(config.Validate(), ShouldBeNil)
+	})
+
+	Convey("Returns every violation with its key and source", t, func() {
+		config := NewConfig(WithSchema(schema))
+		config.Use(NewMapLoader(map[string]interface{}{
+			"db":      map[string]interface{}{"port": 0},
+			"routes":  map[string]interface{}{"http://upstream": "fast"},
+			"servers": []interface{}{"a.example.com", "not a hostname"},
+		}))
+
+		err := config.Validate()
+		So(err, ShouldHaveSameTypeAs, SchemaErrors{})
+		errs := err.(SchemaErrors)
+		So(errs, ShouldHaveLength, 4)
+
+		So(errs[0].Key, ShouldEqual, "db")
+		So(errs[0].Pointer, ShouldEqual, "/db")
+		So(errs[0].Source, ShouldResemble, &Source{Kind: "map", Index: 0})
+		So(errs[0].Error(), ShouldStartWith, "key 'db' from map (position 0): missing properties")
+
+		So(errs[1].Key, ShouldEqual, "db:port")
+		So(errs[1].Error(), ShouldStartWith, "key 'db:port' from map (position 0): ")
+
+		So(errs[2].Key, ShouldEqual, `routes:"http://upstream"`)
+		So(errs[2].Pointer, ShouldEqual, "/routes/http:~1~1upstream")
+
+		So(errs[3].Key, ShouldEqual, "servers:1")
+	})
+
+	Convey("Reports violations of the whole configuration", t, func() {
+		config := NewConfig()
+		err := config.ValidateSchema(schema)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldStartWith, "configuration: missing properties")
+	})
+
+	Convey("Reports keys with the configured delimiter", t, func() {
+		config := NewConfig(WithDelimiter("."))
+		config.Use(NewMapLoader(map[string]interface{}{"db": map[string]interface{}{"host": 5}}))
+		err := config.ValidateSchema(schema)
+		So(err, ShouldNotBeNil)
+		So(err.(SchemaErrors)[0].Key, ShouldEqual, "db.host")
+	})
+
+	Convey("Doesn't validate without a schema", t, func() {
+		So(NewConfig().Validate(), ShouldBeNil)
+	})
+
+	Convey("Rejects reloads that don't match the schema", t, func() {
+		values := map[string]interface{}{"db": map[string]interface{}{"host": "localhost"}}
+		config := NewConfig(WithSchema(schema))
+		config.Use(NewMapLoader(values))
+
+		delete(values["db"].(map[string]interface{}), "host")
+		err := config.Reload()
+		So(err, ShouldHaveSameTypeAs, SchemaErrors{})
+
+		host, err := config.GetString("db:host")
+		So(host, ShouldEqual, "localhost")
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestPointerKeys(t *testing.T) {
+
+	Convey("Converts a JSON pointer into keys", t, func() {
+		So(pointerKeys(""), ShouldBeEmpty)
+		So(pointerKeys("/db/port"), ShouldResemble, []string{"db", "port"})
+		So(pointerKeys("/servers/0"), ShouldResemble, []string{"servers", "0"})
+		So(pointerKeys("/a~1b/c~0d"), ShouldResemble, []string{"a/b", "c~d"})
+	})
+}
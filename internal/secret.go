@@ -0,0 +1,165 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// fileSecretScheme defines the scheme of references to secrets stored in files, e.g. `file:///run/secrets/db_pass`
+const fileSecretScheme = "file"
+
+// SecretResolver resolves the reference in a secret reference, such as `/run/secrets/db_pass` in
+// `file:///run/secrets/db_pass` or `${file:/run/secrets/db_pass}`, to the value of the secret
+type SecretResolver func(reference string) (string, error)
+
+// SecretError describes a secret reference that couldn't be resolved
+type SecretError struct {
+	Key       string
+	Reference string
+	Err       error
+}
+
+// Error formats the secret error, including the key that referenced the secret
+func (err *SecretError) Error() string {
+	return fmt.Sprintf("failed to resolve secret '%s' for key '%s': %v", err.Reference, err.Key, err.Err)
+}
+
+// Unwrap returns the error returned by the secret resolver
+func (err *SecretError) Unwrap() error {
+	return err.Err
+}
+
+// ReadSecretFile resolves a secret stored in a file, removing the trailing newline that most tools write
+func ReadSecretFile(filePath string) (string, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	secret := strings.TrimSuffix(string(contents), "\n")
+	return strings.TrimSuffix(secret, "\r"), nil
+}
+
+// secretCache caches resolved secrets for the lifetime of a state, so each secret is only read once per reload
+type secretCache struct {
+	mutex   sync.Mutex
+	secrets map[string]string
+}
+
+// newSecretCache creates an empty secret cache
+func newSecretCache() *secretCache {
+	return &secretCache{
+		secrets: map[string]string{},
+	}
+}
+
+// resolve resolves a secret reference with the supplied resolver, unless it's already been resolved
+func (cache *secretCache) resolve(scheme string, reference string, resolver SecretResolver) (string, error) {
+	if cache == nil {
+		return resolver(reference)
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	id := scheme + ":" + reference
+	secret, cached := cache.secrets[id]
+	if cached {
+		return secret, nil
+	}
+
+	secret, err := resolver(reference)
+	if err != nil {
+		return "", err
+	}
+	cache.secrets[id] = secret
+	return secret, nil
+}
+
+// resolveSecrets replaces the secret references in the supplied value, which is found at the supplied keys. Maps and
+// slices are modified in place, so the value must be a copy
+func (config *Config) resolveSecrets(currentState *state, keys []string, value interface{}) (interface{}, error) {
+	if len(config.options.secretResolvers) == 0 {
+		return value, nil
+	}
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, element := range typedValue {
+			resolved, err := config.resolveSecrets(currentState, append(keys[:len(keys):len(keys)], key), element)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[key] = resolved
+		}
+	case []interface{}:
+		for i, element := range typedValue {
+			resolved, err := config.resolveSecrets(currentState, append(keys[:len(keys):len(keys)], fmt.Sprint(i)), element)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[i] = resolved
+		}
+	case string:
+		return config.resolveSecretString(currentState, keys, typedValue)
+	}
+
+	return value, nil
+}
+
+// resolveSecretString resolves a string that is a secret reference (`scheme://reference`), or that contains secret
+// references (`${scheme:reference}`). Strings that don't use a registered scheme are returned as they are, and escaped
+// references (`$${scheme:reference}`) are returned without the extra dollar sign
+func (config *Config) resolveSecretString(currentState *state, keys []string, value string) (interface{}, error) {
+	resolve := func(scheme string, reference string, original string) (string, error) {
+		secret, err := currentState.secrets.resolve(scheme, reference, config.options.secretResolvers[scheme])
+		if err != nil {
+			return "", &SecretError{Key: joinKey(keys, config.options.delimiter), Reference: original, Err: err}
+		}
+		return secret, nil
+	}
+
+	scheme, reference, isURL := strings.Cut(value, "://")
+	if isURL && config.isSecretScheme(scheme) {
+		return resolve(scheme, reference, value)
+	}
+
+	builder := strings.Builder{}
+	for {
+		start := strings.Index(value, "${")
+		length := strings.Index(value[start+1:], "}")
+		if start < 0 || length < 0 {
+			builder.WriteString(value)
+			return builder.String(), nil
+		}
+		end := start + 1 + length
+
+		scheme, reference, isReference := strings.Cut(value[start+2:end], ":")
+		switch {
+		case !isReference || !config.isSecretScheme(scheme):
+			builder.WriteString(value[:end+1])
+
+		// Escaped secret references are written as they are, without the extra dollar sign
+		case start > 0 && value[start-1] == '$':
+			builder.WriteString(value[:start-1])
+			builder.WriteString(value[start : end+1])
+
+		default:
+			secret, err := resolve(scheme, reference, value[start:end+1])
+			if err != nil {
+				return nil, err
+			}
+			builder.WriteString(value[:start])
+			builder.WriteString(secret)
+		}
+		value = value[end+1:]
+	}
+}
+
+// isSecretScheme determines if a resolver has been registered for the supplied scheme
+func (config *Config) isSecretScheme(scheme string) bool {
+	_, registered := config.options.secretResolvers[scheme]
+	return registered
+}
@@ -0,0 +1,146 @@
+package internal
+
+import "fmt"
+
+// Source describes where a configuration value was loaded from
+type Source struct {
+	Kind  string
+	Name  string
+	Index int
+}
+
+// String formats the source for use in messages
+func (source Source) String() string {
+	if len(source.Name) == 0 {
+		return fmt.Sprintf("%s (position %d)", source.Kind, source.Index)
+	}
+	return fmt.Sprintf("%s '%s' (position %d)", source.Kind, source.Name, source.Index)
+}
+
+// setSource is the source recorded for values written with Config.Set
+var setSource = Source{Kind: "set", Index: -1}
+
+// Source returns the source of the value at the supplied key. For maps merged from several loaders, the highest
+// priority loader is returned
+func (config *Config) Source(key string) (Source, error) {
+	keys := config.splitKey(key)
+	currentState := config.current()
+	fold := config.options.caseInsensitive
+	_, err := getPath(currentState.values, keys, fold)
+	if err != nil {
+		return Source{}, err
+	}
+
+	source, found := currentState.sourceOf(keys, fold)
+	if !found {
+		return Source{}, fmt.Errorf("no source recorded for key '%s'", key)
+	}
+	return source, nil
+}
+
+// sourceOf returns the source of the highest priority layer containing the supplied key
+func (currentState *state) sourceOf(keys []string, fold bool) (Source, bool) {
+	for _, configLayer := range currentState.allLayers() {
+		if configLayer.has(keys, fold) {
+			return configLayer.sourceOf(keys, fold), true
+		}
+	}
+	return Source{}, false
+}
+
+// layer defines the configuration map produced by a single loader in the loading chain
+type layer struct {
+	source Source
+	loader Loader
+	values map[string]interface{}
+	names  interface{}
+}
+
+// newLayer creates a layer for the map loaded by the supplied loader
+func newLayer(index int, loader Loader, values map[string]interface{}, names map[string]interface{}) *layer {
+	kind, name := describeLoader(loader)
+	return &layer{
+		source: Source{Kind: kind, Name: name, Index: index},
+		loader: loader,
+		values: copyMap(values),
+		names:  names,
+	}
+}
+
+// has determines if the layer contains the supplied key, ignoring case if fold is set
+func (l *layer) has(keys []string, fold bool) bool {
+	_, err := getPath(l.values, keys, fold)
+	return err == nil
+}
+
+// sourceOf returns the source of the supplied key, using the loader's per-key name when one was recorded
+func (l *layer) sourceOf(keys []string, fold bool) Source {
+	source := l.source
+	name := lookupName(l.names, keys, fold)
+	if len(name) > 0 {
+		source.Name = name
+	}
+	return source
+}
+
+// sub returns the layer narrowed to the map at the supplied key, or nil if the layer doesn't contain a map there
+func (l *layer) sub(keys []string, fold bool) *layer {
+	value, err := getPath(l.values, keys, fold)
+	if err != nil {
+		return nil
+	}
+
+	mapValue, castMapValue := value.(map[string]interface{})
+	if !castMapValue {
+		return nil
+	}
+
+	// Narrow the names as well, keeping a single name if the loader recorded one for the whole map
+	var names interface{}
+	switch typedNames := l.names.(type) {
+	case string:
+		names = typedNames
+	case map[string]interface{}:
+		names, _ = getPath(typedNames, keys, fold)
+	}
+
+	return &layer{
+		source: l.source,
+		loader: l.loader,
+		values: mapValue,
+		names:  names,
+	}
+}
+
+// namingLoader defines a loader that records the name each key was loaded from, such as an environment variable
+type namingLoader interface {
+	loadWithNames() (map[string]interface{}, map[string]interface{}, error)
+}
+
+// loadWithNames loads the supplied loader, along with the per-key names it records if it records them
+func loadWithNames(loader Loader) (map[string]interface{}, map[string]interface{}, error) {
+	namedLoader, isNamed := loader.(namingLoader)
+	if !isNamed {
+		loadedMap, err := loader.Load()
+		return loadedMap, nil, err
+	}
+	return namedLoader.loadWithNames()
+}
+
+// lookupName walks a tree of per-key names, returning the name recorded for the supplied key
+func lookupName(names interface{}, keys []string, fold bool) string {
+	for _, key := range keys {
+		switch typedNames := names.(type) {
+		case string:
+			return typedNames
+		case map[string]interface{}:
+			existingKey, _ := findKey(typedNames, key, fold)
+			names = typedNames[existingKey]
+		default:
+			return ""
+		}
+	}
+
+	name, _ := names.(string)
+	return name
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSourceString(t *testing.T) {
+
+	Convey("Formats a source with a name", t, func() {
+		source := Source{Kind: "environment", Name: "APP_DB__HOST", Index: 1}
+		So(source.String(), ShouldEqual, "environment 'APP_DB__HOST' (position 1)")
+	})
+
+	Convey("Formats a source without a name", t, func() {
+		source := Source{Kind: "map", Index: 3}
+		So(source.String(), ShouldEqual, "map (position 3)")
+	})
+}
+
+func TestLookupName(t *testing.T) {
+	names := map[string]interface{}{
+		"db":    map[string]interface{}{"host": "DB__HOST"},
+		"array": "ARRAY",
+	}
+
+	Convey("Returns the name recorded for a nested key", t, func() {
+		So(lookupName(names, []string{"db", "host"}, false), ShouldEqual, "DB__HOST")
+	})
+
+	Convey("Returns the name recorded for a parent of the key", t, func() {
+		So(lookupName(names, []string{"array", "0"}, false), ShouldEqual, "ARRAY")
+	})
+
+	Convey("Returns an empty name when none was recorded", t, func() {
+		So(lookupName(names, []string{"db", "port"}, false), ShouldBeEmpty)
+		So(lookupName(names, []string{"db"}, false), ShouldBeEmpty)
+		So(lookupName(nil, []string{"db"}, false), ShouldBeEmpty)
+	})
+
+	Convey("Returns a name recorded for a whole layer", t, func() {
+		So(lookupName("config.json", []string{"db"}, false), ShouldEqual, "config.json")
+	})
+}
+
+func TestLayerSub(t *testing.T) {
+	l := &layer{
+		source: Source{Kind: "environment", Index: 2},
+		values: map[string]interface{}{"db": map[string]interface{}{"host": "localhost"}, "port": 80},
+		names:  map[string]interface{}{"db": map[string]interface{}{"host": "DB__HOST"}, "port": "PORT"},
+	}
+
+	Convey("Narrows the values and names to the sub map", t, func() {
+		sub := l.sub([]string{"db"}, false)
+		So(sub.values, ShouldResemble, map[string]interface{}{"host": "localhost"})
+		So(sub.sourceOf([]string{"host"}, false), ShouldResemble, Source{Kind: "environment", Name: "DB__HOST", Index: 2})
+	})
+
+	Convey("Returns nil when the key isn't a map", t, func() {
+		So(l.sub([]string{"port"}, false), ShouldBeNil)
+	})
+
+	Convey("Returns nil when the key doesn't exist", t, func() {
+		So(l.sub([]string{"missing"}, false), ShouldBeNil)
+	})
+}
+
+func TestConfigSource(t *testing.T) {
+	t.Setenv("GCONF_SOURCE_TEST_DB__HOST", "env-host")
+
+	config := NewConfig()
+	config.Use(NewEnvironmentLoader(true, "__", "GCONF_SOURCE_TEST_"))
+	config.Use(NewJSONFileLoader("../test/test.json", false))
+	config.Use(NewMapLoader(map[string]interface{}{"db": map[string]interface{}{"port": 5432}}))
+
+	Convey("Reports the environment variable a value came from", t, func() {
+		source, err := config.Source("db:host")
+		So(source, ShouldResemble, Source{Kind: "environment", Name: "GCONF_SOURCE_TEST_DB__HOST", Index: 0})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Reports the file a value came from", t, func() {
+		source, err := config.Source("object:string")
+		So(source, ShouldResemble, Source{Kind: "JSON file", Name: "../test/test.json", Index: 1})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Reports values merged into a map from a later loader", t, func() {
+		source, err := config.Source("db:port")
+		So(source, ShouldResemble, Source{Kind: "map", Index: 2})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Reports values written with Set", t, func() {
+		So(config.Set("set:value", 1), ShouldBeNil)
+		source, err := config.Source("set:value")
+		So(source, ShouldResemble, setSource)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Reports sources from a sub config", t, func() {
+		subConfig, err := config.GetSubConfig("db")
+		So(err, ShouldBeNil)
+
+		source, err := subConfig.Source("host")
+		So(source, ShouldResemble, Source{Kind: "environment", Name: "GCONF_SOURCE_TEST_DB__HOST", Index: 0})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns an error when the key doesn't exist", t, func() {
+		_, err := config.Source("db:missing")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Returns an error when no source was recorded", t, func() {
+		_, err := newConfig(&state{values: map[string]interface{}{"one": 1}}, defaultOptions()).Source("one")
+		So(err, ShouldNotBeNil)
+	})
+}
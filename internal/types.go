@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ByteSize defines a number of bytes, which can be loaded from strings like "512MiB" or "10MB"
+type ByteSize uint64
+
+// Byte size units
+const (
+	Byte     ByteSize = 1
+	Kilobyte          = 1000 * Byte
+	Megabyte          = 1000 * Kilobyte
+	Gigabyte          = 1000 * Megabyte
+	Terabyte          = 1000 * Gigabyte
+	Petabyte          = 1000 * Terabyte
+	Kibibyte          = 1024 * Byte
+	Mebibyte          = 1024 * Kibibyte
+	Gibibyte          = 1024 * Mebibyte
+	Tebibyte          = 1024 * Gibibyte
+	Pebibyte          = 1024 * Tebibyte
+)
+
+// byteSizeUnits maps the unit suffixes to their sizes, in the order they're used for formatting. Suffixes are matched
+// regardless of case when parsing
+var byteSizeUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"PiB", Pebibyte}, {"TiB", Tebibyte}, {"GiB", Gibibyte}, {"MiB", Mebibyte}, {"KiB", Kibibyte},
+	{"PB", Petabyte}, {"TB", Terabyte}, {"GB", Gigabyte}, {"MB", Megabyte}, {"KB", Kilobyte},
+	{"P", Petabyte}, {"T", Terabyte}, {"G", Gigabyte}, {"M", Megabyte}, {"K", Kilobyte},
+	{"B", Byte},
+}
+
+// String formats the byte size using the largest unit that represents it exactly, preferring binary units
+func (size ByteSize) String() string {
+	for _, unit := range byteSizeUnits[:10] {
+		if size >= unit.size && size%unit.size == 0 {
+			return fmt.Sprintf("%d%s", size/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", uint64(size))
+}
+
+// ParseByteSize parses a byte size string like "512MiB", "10MB", "1.5 GB" or "1024"
+func ParseByteSize(value string) (ByteSize, error) {
+	trimmed := strings.TrimSpace(value)
+
+	// Find the unit suffix, defaulting to bytes
+	unitSize := Byte
+	for _, unit := range byteSizeUnits {
+		if len(trimmed) > len(unit.suffix) && strings.EqualFold(trimmed[len(trimmed)-len(unit.suffix):], unit.suffix) {
+			unitSize = unit.size
+			trimmed = strings.TrimSpace(trimmed[:len(trimmed)-len(unit.suffix)])
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || number < 0 || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("invalid byte size '%s'", value)
+	}
+
+	bytes := number * float64(unitSize)
+	if bytes >= math.MaxUint64 || bytes != math.Trunc(bytes) {
+		return 0, fmt.Errorf("invalid byte size '%s'", value)
+	}
+	return ByteSize(bytes), nil
+}
+
+// Types with special conversion rules
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	byteSizeType = reflect.TypeOf(ByteSize(0))
+)
+
+// coerceDuration converts a duration string or a number of seconds into a duration
+func coerceDuration(source reflect.Value, target reflect.Type) (reflect.Value, error) {
+	switch source.Kind() {
+	case reflect.String:
+		duration, err := time.ParseDuration(source.String())
+		if err == nil {
+			return reflect.ValueOf(duration), nil
+		}
+
+		// Accept a plain number of seconds as well
+		seconds, err := strconv.ParseFloat(source.String(), 64)
+		if err != nil {
+			return reflect.Value{}, conversionError(source, target, "not a duration")
+		}
+		return secondsToDuration(source, target, seconds)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		seconds, err := coerceValue(source.Interface(), reflect.TypeOf(float64(0)))
+		if err != nil {
+			return reflect.Value{}, conversionError(source, target, err.(*ConversionError).Reason)
+		}
+		return secondsToDuration(source, target, seconds.Float())
+	default:
+		return reflect.Value{}, conversionError(source, target, "unsupported conversion")
+	}
+}
+
+// secondsToDuration converts a number of seconds into a duration, failing if it's out of range
+func secondsToDuration(source reflect.Value, target reflect.Type, seconds float64) (reflect.Value, error) {
+	nanoseconds := seconds * float64(time.Second)
+	if math.IsNaN(nanoseconds) || nanoseconds >= math.MaxInt64 || nanoseconds < math.MinInt64 {
+		return reflect.Value{}, conversionError(source, target, "value overflows the target type")
+	}
+	return reflect.ValueOf(time.Duration(nanoseconds)), nil
+}
+
+// coerceTime converts an RFC 3339 string or a Unix timestamp (in seconds) into a time
+func coerceTime(source reflect.Value, target reflect.Type) (reflect.Value, error) {
+	switch source.Kind() {
+	case reflect.String:
+		parsed, err := time.Parse(time.RFC3339Nano, source.String())
+		if err == nil {
+			return reflect.ValueOf(parsed), nil
+		}
+
+		// Accept a Unix timestamp string as well
+		seconds, err := strconv.ParseFloat(source.String(), 64)
+		if err != nil {
+			return reflect.Value{}, conversionError(source, target, "not an RFC 3339 time or Unix timestamp")
+		}
+		return reflect.ValueOf(unixTime(seconds)), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(time.Unix(source.Int(), 0)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if source.Uint() > math.MaxInt64 {
+			return reflect.Value{}, conversionError(source, target, "value overflows the target type")
+		}
+		return reflect.ValueOf(time.Unix(int64(source.Uint()), 0)), nil
+	case reflect.Float32, reflect.Float64:
+		return reflect.ValueOf(unixTime(source.Float())), nil
+	default:
+		return reflect.Value{}, conversionError(source, target, "unsupported conversion")
+	}
+}
+
+// unixTime converts a fractional Unix timestamp into a time
+func unixTime(seconds float64) time.Time {
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(fraction*float64(time.Second)))
+}
+
+// coerceByteSize converts a byte size string or a number of bytes into a byte size
+func coerceByteSize(source reflect.Value, target reflect.Type) (reflect.Value, error) {
+	if source.Kind() != reflect.String {
+		return coerceUint(source, target)
+	}
+
+	size, err := ParseByteSize(source.String())
+	if err != nil {
+		return reflect.Value{}, conversionError(source, target, "not a byte size")
+	}
+	return reflect.ValueOf(size), nil
+}
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseByteSize(t *testing.T) {
+
+	Convey("Parses plain numbers as bytes", t, func() {
+		size, err := ParseByteSize("1024")
+		So(size, ShouldEqual, 1024)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Parses binary units", t, func() {
+		size, err := ParseByteSize("512MiB")
+		So(size, ShouldEqual, 512*Mebibyte)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Parses decimal units", t, func() {
+		size, err := ParseByteSize("10MB")
+		So(size, ShouldEqual, 10*Megabyte)
+		So(err, ShouldBeNil)
+
+		size, err = ParseByteSize("2k")
+		So(size, ShouldEqual, 2*Kilobyte)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Parses fractions, spaces and lower case units", t, func() {
+		size, err := ParseByteSize(" 1.5 gib ")
+		So(size, ShouldEqual, 3*Gibibyte/2)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns an error for invalid sizes", t, func() {
+		for _, value := range []string{"", "MiB", "-1KB", "abc", "0.5B", "100000PB"} {
+			_, err := ParseByteSize(value)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
+
+func TestByteSizeString(t *testing.T) {
+
+	Convey("Formats sizes with the largest exact unit", t, func() {
+		So((512 * Mebibyte).String(), ShouldEqual, "512MiB")
+		So((10 * Megabyte).String(), ShouldEqual, "10MB")
+		So((1536 * Kibibyte).String(), ShouldEqual, "1536KiB")
+		So(ByteSize(1023).String(), ShouldEqual, "1023B")
+		So(ByteSize(0).String(), ShouldEqual, "0B")
+	})
+}
+
+func TestCoerceSpecialTypes(t *testing.T) {
+
+	Convey("Converts durations", t, func() {
+
+		Convey("From durations", func() {
+			result, err := coerce[time.Duration](3 * time.Second)
+			So(result, ShouldEqual, 3*time.Second)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From duration strings", func() {
+			result, err := coerce[time.Duration]("1m30s")
+			So(result, ShouldEqual, 90*time.Second)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From numbers of seconds", func() {
+			result, err := coerce[time.Duration](5)
+			So(result, ShouldEqual, 5*time.Second)
+			So(err, ShouldBeNil)
+
+			result, err = coerce[time.Duration](1.5)
+			So(result, ShouldEqual, 1500*time.Millisecond)
+			So(err, ShouldBeNil)
+
+			result, err = coerce[time.Duration]("2")
+			So(result, ShouldEqual, 2*time.Second)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error for invalid values", func() {
+			_, err := coerce[time.Duration]("soon")
+			So(err, ShouldNotBeNil)
+
+			_, err = coerce[time.Duration](1e20)
+			So(err, ShouldNotBeNil)
+
+			_, err = coerce[time.Duration](true)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Converts times", t, func() {
+		expected := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		Convey("From RFC 3339 strings", func() {
+			result, err := coerce[time.Time]("2024-01-02T03:04:05Z")
+			So(result.Equal(expected), ShouldBeTrue)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From Unix timestamps", func() {
+			result, err := coerce[time.Time](expected.Unix())
+			So(result.Equal(expected), ShouldBeTrue)
+			So(err, ShouldBeNil)
+
+			result, err = coerce[time.Time](float64(expected.Unix()) + 0.5)
+			So(result.Equal(expected.Add(500*time.Millisecond)), ShouldBeTrue)
+			So(err, ShouldBeNil)
+
+			result, err = coerce[time.Time]("1704164645")
+			So(result.Equal(expected), ShouldBeTrue)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error for invalid values", func() {
+			_, err := coerce[time.Time]("yesterday")
+			So(err, ShouldNotBeNil)
+
+			_, err = coerce[time.Time](true)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Converts byte sizes", t, func() {
+
+		Convey("From strings", func() {
+			result, err := coerce[ByteSize]("10MB")
+			So(result, ShouldEqual, 10*Megabyte)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("From numbers of bytes", func() {
+			result, err := coerce[ByteSize](2048.0)
+			So(result, ShouldEqual, 2*Kibibyte)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Returns an error for invalid values", func() {
+			_, err := coerce[ByteSize]("lots")
+			So(err, ShouldNotBeNil)
+
+			_, err = coerce[ByteSize](-1)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("Converts slices of special types", t, func() {
+		result, err := coerce[[]time.Duration]([]interface{}{"1s", 2})
+		So(result, ShouldResemble, []time.Duration{time.Second, 2 * time.Second})
+		So(err, ShouldBeNil)
+	})
+}
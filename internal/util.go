@@ -2,8 +2,9 @@ package internal
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -15,23 +16,74 @@ func has(m map[string]interface{}, key string) bool {
 	return keyExists
 }
 
-// set sets the value of a nested key in the supplied map
+// findKey finds the key in the supplied map that matches the supplied key, ignoring case if fold is set. Exact matches
+// are preferred over case-insensitive ones
+func findKey(m map[string]interface{}, key string, fold bool) (string, bool) {
+	if has(m, key) {
+		return key, true
+	}
+
+	if fold {
+		for existingKey := range m {
+			if strings.EqualFold(existingKey, key) {
+				return existingKey, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// set sets the value of a nested key in the supplied map, failing if the key is already present. Numeric keys index
+// into existing slices, and the index just past the end of a slice appends to it
 func set(m map[string]interface{}, keys []string, value interface{}) (map[string]interface{}, error) {
+	return setPath(m, keys, value, setOptions{})
+}
+
+// setOptions defines how setPath treats existing values, and the parent keys and delimiter it reports full keys with
+type setOptions struct {
+	replace   bool
+	fold      bool
+	delimiter string
+	parents   []string
+}
+
+// keyPath joins the parent keys and the supplied keys into the full key used in error messages
+func (options setOptions) keyPath(keys ...string) string {
+	delimiter := options.delimiter
+	if len(delimiter) == 0 {
+		delimiter = defaultDelimiter
+	}
+	return joinKey(append(options.parents[:len(options.parents):len(options.parents)], keys...), delimiter)
+}
+
+// child returns the options for setting a value below the supplied key
+func (options setOptions) child(key string) setOptions {
+	options.parents = append(options.parents[:len(options.parents):len(options.parents)], key)
+	return options
+}
+
+// setPath sets the value of a nested key in the supplied map. Existing values are replaced if replace is set, and
+// existing keys are matched case-insensitively if fold is set
+func setPath(m map[string]interface{}, keys []string, value interface{}, options setOptions) (map[string]interface{}, error) {
 
 	// If we're not adding interface{} more keys, return this map
 	if len(keys) == 0 {
 		return m, nil
 	}
 
-	key := keys[0]
-	keyExists := has(m, key)
+	// Write into the existing key if there is one, keeping its case
+	key, keyExists := findKey(m, keys[0], options.fold)
+	if !keyExists {
+		key = keys[0]
+	}
 
 	// Last key, just write it in and return
 	if len(keys) == 1 {
 
 		// Key that we're trying to set already exists
-		if keyExists {
-			return m, fmt.Errorf("configuration option '%s' already present", key)
+		if keyExists && !options.replace {
+			return m, fmt.Errorf("configuration option '%s' already present", options.keyPath(key))
 		}
 
 		m[key] = value
@@ -39,66 +91,156 @@ func set(m map[string]interface{}, keys []string, value interface{}) (map[string
 	}
 
 	// Initialize a new map. We'll put this in the parent map if there isn't already a key there
-	castValue := map[string]interface{}{}
-
-	// The key already exists but if it's a map we can still go into it
+	var child interface{} = map[string]interface{}{}
 	if keyExists {
-		var castSuccessfully bool
-		castValue, castSuccessfully = m[key].(map[string]interface{})
-
-		if !castSuccessfully {
-			return m, fmt.Errorf("configuration option '%s' already present and not a map", key)
-		}
+		child = m[key]
 	}
 
 	// Recurse and add the next nested value
-	submap, err := set(castValue, keys[1:], value)
+	newChild, err := setNested(child, keys[1:], value, options.child(key))
 	if err != nil {
 		return m, err
 	}
 
-	m[key] = submap
+	m[key] = newChild
 	return m, nil
 }
 
-// get gets the value of a nested key in the supplied map
-func get(m map[string]interface{}, keys []string) (interface{}, error) {
-
-	key := keys[0]
-	keyExists := has(m, key)
+// setSlice sets the value of a nested key in the supplied slice, returning the (possibly grown) slice
+func setSlice(s []interface{}, keys []string, value interface{}, options setOptions) ([]interface{}, error) {
 
-	if !keyExists {
-		return nil, fmt.Errorf("key '%s' was not found", key)
+	// The next index is allowed too, which appends to the slice
+	index, err := sliceIndex(options.keyPath(), keys[0], len(s), true)
+	if err != nil {
+		return s, err
 	}
+	exists := index < len(s)
 
-	// If this is the last key, return the value
+	// Last key, write it in or append it
 	if len(keys) == 1 {
-		return m[key], nil
+		if !exists {
+			return append(s, value), nil
+		}
+
+		if !options.replace {
+			return s, fmt.Errorf("configuration option '%s' already present", options.keyPath(keys[0]))
+		}
+
+		s[index] = value
+		return s, nil
+	}
+
+	// Not the last key, recurse into the existing element or a new map
+	var child interface{} = map[string]interface{}{}
+	if exists {
+		child = s[index]
+	}
+
+	newChild, err := setNested(child, keys[1:], value, options.child(keys[0]))
+	if err != nil {
+		return s, err
+	}
+
+	if !exists {
+		return append(s, newChild), nil
+	}
+
+	s[index] = newChild
+	return s, nil
+}
+
+// setNested sets the value of a nested key inside the supplied map or slice, which is found at the options' parent keys
+func setNested(container interface{}, keys []string, value interface{}, options setOptions) (interface{}, error) {
+	switch typedContainer := container.(type) {
+	case map[string]interface{}:
+		return setPath(typedContainer, keys, value, options)
+	case []interface{}:
+		return setSlice(typedContainer, keys, value, options)
+	default:
+		if options.replace {
+			return setPath(map[string]interface{}{}, keys, value, options)
+		}
+		return container, fmt.Errorf("configuration option '%s' already present and not a map or slice", options.keyPath())
+	}
+}
+
+// get gets the value of a nested key in the supplied map. Numeric keys index into slices
+func get(m map[string]interface{}, keys []string) (interface{}, error) {
+	return getPath(m, keys, false)
+}
+
+// getPath gets the value of a nested key in the supplied map, matching keys case-insensitively if fold is set
+func getPath(m map[string]interface{}, keys []string, fold bool) (interface{}, error) {
+	var value interface{} = m
+
+	for i, key := range keys {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			existingKey, keyExists := findKey(container, key, fold)
+			if !keyExists {
+				return nil, fmt.Errorf("key '%s' was not found", key)
+			}
+			value = container[existingKey]
+		case []interface{}:
+			index, err := sliceIndex(keys[i-1], key, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			value = container[index]
+		default:
+			return nil, fmt.Errorf("key '%s' is not a map or slice that can contain sub keys", keys[i-1])
+		}
+	}
+
+	return value, nil
+}
+
+// sliceIndex parses a key used to index into the slice at the parent key, failing if it's not a valid index or is
+// out of range for the supplied length. Appendable slices also accept the index just past the end
+func sliceIndex(parent string, key string, length int, appendable bool) (int, error) {
+	index, err := strconv.Atoi(key)
+	if err != nil || index < 0 || strconv.Itoa(index) != key {
+		return 0, fmt.Errorf("key '%s' is a slice and can only be indexed with numbers, not '%s'", parent, key)
 	}
 
-	// Not the last key in the chain, make sure the next key is a map
-	mapValue, castMapValue := m[key].(map[string]interface{})
-	if !castMapValue {
-		return nil, fmt.Errorf("key '%s' is not a map that can contain sub keys", key)
+	if index > length || (index == length && !appendable) {
+		return 0, fmt.Errorf("index %d is out of range for key '%s' with %d elements", index, parent, length)
 	}
 
-	return get(mapValue, keys[1:])
+	return index, nil
 }
 
 // merge merges two maps recursively
 func merge(map1 map[string]interface{}, map2 map[string]interface{}) map[string]interface{} {
+	return mergeMaps(map1, map2, false)
+}
 
-	for key, value := range map2 {
+// mergeMaps merges two maps recursively, keeping the values in the first map. If fold is set, keys are matched
+// case-insensitively and the first map's case is kept
+func mergeMaps(map1 map[string]interface{}, map2 map[string]interface{}, fold bool) map[string]interface{} {
+
+	// Go through the keys in order when ignoring case, so it's predictable which of several matching keys wins
+	keys := make([]string, 0, len(map2))
+	for key := range map2 {
+		keys = append(keys, key)
+	}
+	if fold {
+		sort.Strings(keys)
+	}
+
+	for _, key := range keys {
+		value := map2[key]
 
 		// If we don't have the key in map 1, just take the whole thing
-		if !has(map1, key) {
+		existingKey, keyExists := findKey(map1, key, fold)
+		if !keyExists {
 			map1[key] = value
 			continue
 		}
 
 		// We have the key in map 1 and map 2, let's see if it's a map in both so we can merge those
-		map1Value, castMap1Value := map1[key].(map[string]interface{})
-		map2Value, castMap2Value := map2[key].(map[string]interface{})
+		map1Value, castMap1Value := map1[existingKey].(map[string]interface{})
+		map2Value, castMap2Value := value.(map[string]interface{})
 
 		// If we failed to cast one of these to a map then we can't merge them. Just ignore the key
 		if !castMap1Value || !castMap2Value {
@@ -106,12 +248,79 @@ func merge(map1 map[string]interface{}, map2 map[string]interface{}) map[string]
 		}
 
 		// Both of them are maps, keep merging
-		map1[key] = merge(map1Value, map2Value)
+		map1[existingKey] = mergeMaps(map1Value, map2Value, fold)
 	}
 
 	return map1
 }
 
+// copyMap deep copies the supplied map, including any nested maps and slices
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	copied := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		copied[key] = copyValue(value)
+	}
+	return copied
+}
+
+// copyValue deep copies the supplied value if it's a map or slice of any type, returning other values untouched
+func copyValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		return copyMap(typedValue)
+	case []interface{}:
+		copied := make([]interface{}, len(typedValue))
+		for i, element := range typedValue {
+			copied[i] = copyValue(element)
+		}
+		return copied
+	case nil:
+		return nil
+	}
+
+	// Typed maps and slices, such as a []string passed to Set, are copied through reflection
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Map:
+		if reflected.IsNil() {
+			return value
+		}
+		copied := reflect.MakeMapWithSize(reflected.Type(), reflected.Len())
+		iterator := reflected.MapRange()
+		for iterator.Next() {
+			copied.SetMapIndex(iterator.Key(), copyReflected(iterator.Value()))
+		}
+		return copied.Interface()
+	case reflect.Slice:
+		if reflected.IsNil() {
+			return value
+		}
+		copied := reflect.MakeSlice(reflected.Type(), reflected.Len(), reflected.Len())
+		for i := 0; i < reflected.Len(); i++ {
+			copied.Index(i).Set(copyReflected(reflected.Index(i)))
+		}
+		return copied.Interface()
+	default:
+		return value
+	}
+}
+
+// copyReflected deep copies a map or slice element, keeping elements that can't be copied (such as nil interfaces)
+func copyReflected(element reflect.Value) reflect.Value {
+	if !element.CanInterface() || (element.Kind() == reflect.Interface && element.IsNil()) {
+		return element
+	}
+	copied := copyValue(element.Interface())
+	if copied == nil {
+		return reflect.Zero(element.Type())
+	}
+	return reflect.ValueOf(copied).Convert(element.Type())
+}
+
 // parseString parses a string into a variety of types
 func parseString(value string) interface{} {
 
@@ -186,67 +395,3 @@ func convertDurationStrings(m map[string]interface{}) map[string]interface{} {
 
 	return m
 }
-
-// splitKey splits the supplied key into an array
-func splitKey(key string) []string {
-	return strings.Split(key, ":")
-}
-
-// cast casts the supplied object into the supplied type
-func cast[T any](obj interface{}) (T, error) {
-	value, success := obj.(T)
-	if !success {
-		return value, fmt.Errorf("failed to cast value to %T", *new(T))
-	}
-	return value, nil
-}
-
-// castIntegerSlice casts a value to an integer slice
-func castIntegerSlice(obj interface{}) ([]int, error) {
-
-	// Try a regular cast
-	value, success := obj.([]int)
-	if success {
-		return value, nil
-	}
-
-	// Try to cast it into a float slice (the default when reading JSON)
-	floatSlice, success := obj.([]float64)
-	if !success {
-		return nil, errors.New("failed to cast value to integer slice")
-	}
-
-	// Managed to convert to a float slice, convert to an integer slice
-	intSlice := make([]int, len(floatSlice))
-	for i, v := range floatSlice {
-		intSlice[i] = int(v)
-
-		if float64(intSlice[i]) != v {
-			return nil, errors.New("failed to cast value to integer slice")
-		}
-	}
-
-	return intSlice, nil
-}
-
-// castInteger casts a value to an integer
-func castInteger(obj interface{}) (int, error) {
-
-	// Try a regular integer cast
-	value, success := obj.(int)
-	if success {
-		return value, nil
-	}
-
-	// Try to cast it into a float (the default when reading JSON) and then convert to int
-	floatValue, cast := obj.(float64)
-	if !cast {
-		return 0, errors.New("failed to cast value to integer")
-	}
-
-	if float64(int(floatValue)) != floatValue {
-		return 0, errors.New("failed to cast value to integer")
-	}
-
-	return int(floatValue), nil
-}
@@ -20,6 +20,33 @@ func TestHas(t *testing.T) {
 	})
 }
 
+func TestFindKey(t *testing.T) {
+	m := map[string]interface{}{"dbHost": "a", "DBHOST": "b"}
+
+	Convey("Finds an exact match", t, func() {
+		key, found := findKey(m, "DBHOST", false)
+		So(key, ShouldEqual, "DBHOST")
+		So(found, ShouldBeTrue)
+	})
+
+	Convey("Doesn't ignore case unless fold is set", t, func() {
+		_, found := findKey(m, "dbhost", false)
+		So(found, ShouldBeFalse)
+	})
+
+	Convey("Prefers an exact match when ignoring case", t, func() {
+		key, found := findKey(m, "dbHost", true)
+		So(key, ShouldEqual, "dbHost")
+		So(found, ShouldBeTrue)
+	})
+
+	Convey("Finds a key that differs in case when ignoring case", t, func() {
+		key, found := findKey(map[string]interface{}{"dbHost": "a"}, "DBHOST", true)
+		So(key, ShouldEqual, "dbHost")
+		So(found, ShouldBeTrue)
+	})
+}
+
 func TestSet(t *testing.T) {
 
 	Convey("Returns the input map when no keys are specified", t, func() {
@@ -49,14 +76,87 @@ func TestSet(t *testing.T) {
 	Convey("Returns an error when a nested key is already present", t, func() {
 		result, err := set(map[string]interface{}{"a": map[string]interface{}{"b": true}}, []string{"a", "b"}, nil)
 		So(result, ShouldResemble, map[string]interface{}{"a": map[string]interface{}{"b": true}})
-		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "configuration option 'a:b' already present")
 	})
 
 	Convey("Returns an error when a nested key is already present as a nested key", t, func() {
 		result, err := set(map[string]interface{}{"a": map[string]interface{}{"b": true}}, []string{"a", "b", "c"}, nil)
 		So(result, ShouldResemble, map[string]interface{}{"a": map[string]interface{}{"b": true}})
+		So(err.Error(), ShouldEqual, "configuration option 'a:b' already present and not a map or slice")
+	})
+}
+
+func TestSetSlices(t *testing.T) {
+	slice := func() map[string]interface{} {
+		return map[string]interface{}{"servers": []interface{}{map[string]interface{}{"host": "a"}}}
+	}
+
+	Convey("Sets a key inside an existing slice element", t, func() {
+		result, err := set(slice(), []string{"servers", "0", "port"}, 80)
+		So(result, ShouldResemble, map[string]interface{}{"servers": []interface{}{map[string]interface{}{"host": "a", "port": 80}}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Grows the slice when setting the next index", t, func() {
+		result, err := set(slice(), []string{"servers", "1"}, "b")
+		So(result, ShouldResemble, map[string]interface{}{"servers": []interface{}{map[string]interface{}{"host": "a"}, "b"}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Grows the slice with a new map when setting a key below the next index", t, func() {
+		result, err := set(slice(), []string{"servers", "1", "host"}, "b")
+		So(result, ShouldResemble, map[string]interface{}{"servers": []interface{}{
+			map[string]interface{}{"host": "a"},
+			map[string]interface{}{"host": "b"},
+		}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns an error when the element is already present", t, func() {
+		_, err := set(slice(), []string{"servers", "0"}, "b")
+		So(err.Error(), ShouldEqual, "configuration option 'servers:0' already present")
+
+		_, err = setPath(slice(), []string{"servers", "0", "host"}, "b", setOptions{delimiter: "."})
+		So(err.Error(), ShouldEqual, "configuration option 'servers.0.host' already present")
+	})
+
+	Convey("Returns an error when the index is past the next index", t, func() {
+		_, err := set(slice(), []string{"servers", "2"}, "b")
+		So(err.Error(), ShouldEqual, "index 2 is out of range for key 'servers' with 1 elements")
+	})
+
+	Convey("Returns an error when the index isn't a number", t, func() {
+		_, err := set(slice(), []string{"servers", "first"}, "b")
 		So(err, ShouldNotBeNil)
 	})
+
+	Convey("Doesn't create slices for missing keys", t, func() {
+		result, err := set(map[string]interface{}{}, []string{"servers", "0"}, "a")
+		So(result, ShouldResemble, map[string]interface{}{"servers": map[string]interface{}{"0": "a"}})
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestSetPathReplace(t *testing.T) {
+	replace := setOptions{replace: true}
+
+	Convey("Replaces existing values", t, func() {
+		result, err := setPath(map[string]interface{}{"a": map[string]interface{}{"b": true}}, []string{"a", "b"}, false, replace)
+		So(result, ShouldResemble, map[string]interface{}{"a": map[string]interface{}{"b": false}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Replaces existing slice elements", t, func() {
+		result, err := setPath(map[string]interface{}{"a": []interface{}{1, 2}}, []string{"a", "1"}, 3, replace)
+		So(result, ShouldResemble, map[string]interface{}{"a": []interface{}{1, 3}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Replaces values that can't contain sub keys with maps", t, func() {
+		result, err := setPath(map[string]interface{}{"a": true}, []string{"a", "b"}, 1, replace)
+		So(result, ShouldResemble, map[string]interface{}{"a": map[string]interface{}{"b": 1}})
+		So(err, ShouldBeNil)
+	})
 }
 
 func TestGet(t *testing.T) {
@@ -86,6 +186,82 @@ func TestGet(t *testing.T) {
 	})
 }
 
+func TestGetPath(t *testing.T) {
+	m := map[string]interface{}{"Db": map[string]interface{}{"Hosts": []interface{}{"a"}}}
+
+	Convey("Matches keys that differ in case when fold is set", t, func() {
+		result, err := getPath(m, []string{"db", "HOSTS", "0"}, true)
+		So(result, ShouldEqual, "a")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Doesn't match keys that differ in case unless fold is set", t, func() {
+		result, err := getPath(m, []string{"db", "hosts", "0"}, false)
+		So(result, ShouldBeNil)
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestSetPath(t *testing.T) {
+
+	Convey("Writes into existing keys that differ in case when fold is set", t, func() {
+		result, err := setPath(map[string]interface{}{"Db": map[string]interface{}{"host": "a"}}, []string{"db", "port"}, 1, setOptions{fold: true})
+		So(result, ShouldResemble, map[string]interface{}{"Db": map[string]interface{}{"host": "a", "port": 1}})
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Treats keys that differ in case as existing when fold is set", t, func() {
+		_, err := setPath(map[string]interface{}{"dbHost": "a"}, []string{"DBHOST"}, "b", setOptions{fold: true})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Replaces the value while keeping the existing key's case", t, func() {
+		result, err := setPath(map[string]interface{}{"dbHost": "a"}, []string{"DBHOST"}, "b", setOptions{replace: true, fold: true})
+		So(result, ShouldResemble, map[string]interface{}{"dbHost": "b"})
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestGetSlices(t *testing.T) {
+	m := map[string]interface{}{"servers": []interface{}{map[string]interface{}{"host": "a"}, "b"}}
+
+	Convey("Gets a slice element", t, func() {
+		result, err := get(m, []string{"servers", "1"})
+		So(result, ShouldEqual, "b")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Gets a key inside a slice element", t, func() {
+		result, err := get(m, []string{"servers", "0", "host"})
+		So(result, ShouldEqual, "a")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Returns an error when the index is out of range", t, func() {
+		result, err := get(m, []string{"servers", "2"})
+		So(result, ShouldBeNil)
+		So(err.Error(), ShouldEqual, "index 2 is out of range for key 'servers' with 2 elements")
+	})
+
+	Convey("Returns an error when the index isn't a number", t, func() {
+		for _, index := range []string{"host", "-1", "+1", "01"} {
+			_, err := get(m, []string{"servers", index})
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Returns an error when indexing into a value that isn't a map or slice", t, func() {
+		_, err := get(m, []string{"servers", "1", "0"})
+		So(err.Error(), ShouldEqual, "key '1' is not a map or slice that can contain sub keys")
+	})
+
+	Convey("Returns the whole map when there are no keys", t, func() {
+		result, err := get(m, []string{})
+		So(result, ShouldResemble, m)
+		So(err, ShouldBeNil)
+	})
+}
+
 func TestMerge(t *testing.T) {
 
 	Convey("Merges non-nested keys", t, func() {
@@ -109,6 +285,68 @@ func TestMerge(t *testing.T) {
 	})
 }
 
+func TestMergeMaps(t *testing.T) {
+
+	Convey("Keeps keys that differ in case apart unless fold is set", t, func() {
+		result := mergeMaps(map[string]interface{}{"dbHost": 1}, map[string]interface{}{"DBHOST": 2}, false)
+		So(result, ShouldResemble, map[string]interface{}{"dbHost": 1, "DBHOST": 2})
+	})
+
+	Convey("Keeps the first map's key and value when ignoring case", t, func() {
+		result := mergeMaps(map[string]interface{}{"dbHost": 1}, map[string]interface{}{"DBHOST": 2}, true)
+		So(result, ShouldResemble, map[string]interface{}{"dbHost": 1})
+	})
+
+	Convey("Merges nested maps whose keys differ in case when ignoring case", t, func() {
+		result := mergeMaps(map[string]interface{}{"Db": map[string]interface{}{"host": 1}}, map[string]interface{}{"db": map[string]interface{}{"HOST": 2, "port": 3}}, true)
+		So(result, ShouldResemble, map[string]interface{}{"Db": map[string]interface{}{"host": 1, "port": 3}})
+	})
+
+	Convey("Keeps the first key in order when the second map has several keys that differ in case", t, func() {
+		result := mergeMaps(map[string]interface{}{}, map[string]interface{}{"dbhost": 1, "DBHOST": 2}, true)
+		So(result, ShouldResemble, map[string]interface{}{"DBHOST": 2})
+	})
+}
+
+func TestCopyMap(t *testing.T) {
+
+	Convey("Returns nil for a nil map", t, func() {
+		So(copyMap(nil), ShouldBeNil)
+	})
+
+	Convey("Deep copies nested maps and slices", t, func() {
+		original := map[string]interface{}{
+			"map":   map[string]interface{}{"one": 1},
+			"slice": []interface{}{map[string]interface{}{"two": 2}},
+		}
+		copied := copyMap(original)
+		So(copied, ShouldResemble, original)
+
+		copied["map"].(map[string]interface{})["one"] = 2
+		copied["slice"].([]interface{})[0].(map[string]interface{})["two"] = 3
+		So(original["map"], ShouldResemble, map[string]interface{}{"one": 1})
+		So(original["slice"], ShouldResemble, []interface{}{map[string]interface{}{"two": 2}})
+	})
+
+	Convey("Deep copies typed maps and slices", t, func() {
+		original := map[string]interface{}{
+			"strings": []string{"a", "b"},
+			"labels":  map[string]string{"one": "1"},
+			"nested":  map[string][]int{"ports": {80}},
+			"nil":     []string(nil),
+		}
+		copied := copyMap(original)
+		So(copied, ShouldResemble, original)
+
+		copied["strings"].([]string)[0] = "changed"
+		copied["labels"].(map[string]string)["one"] = "changed"
+		copied["nested"].(map[string][]int)["ports"][0] = 8080
+		So(original["strings"], ShouldResemble, []string{"a", "b"})
+		So(original["labels"], ShouldResemble, map[string]string{"one": "1"})
+		So(original["nested"], ShouldResemble, map[string][]int{"ports": {80}})
+	})
+}
+
 func TestParseString(t *testing.T) {
 
 	Convey("Parses booleans", t, func() {
@@ -188,183 +426,3 @@ func TestConvertDurationStrings(t *testing.T) {
 		So(m, ShouldResemble, map[string]interface{}{"a": map[string]interface{}{"b": 3 * time.Second}})
 	})
 }
-
-func TestCasts(t *testing.T) {
-
-	Convey("cast", t, func() {
-
-		Convey("Casts maps", func() {
-
-			Convey("Casts an empty interface into a map", func() {
-				result, err := cast[map[string]interface{}](map[string]interface{}{})
-				So(result, ShouldResemble, map[string]interface{}{})
-				So(err, ShouldBeNil)
-			})
-
-			Convey("Returns an error if the cast can't be done", func() {
-				result, err := cast[map[string]interface{}](5)
-				So(result, ShouldBeNil)
-				So(err, ShouldNotBeNil)
-			})
-		})
-
-		Convey("Casts slices", func() {
-
-			Convey("Casts an empty interface into a slice", func() {
-				result, err := cast[[]interface{}]([]interface{}{1, "two"})
-				So(result, ShouldResemble, []interface{}{1, "two"})
-				So(err, ShouldBeNil)
-			})
-
-			Convey("Returns an error if the cast can't be done", func() {
-				result, err := cast[[]interface{}](5)
-				So(result, ShouldBeNil)
-				So(err, ShouldNotBeNil)
-			})
-		})
-
-		Convey("Casts string slices", func() {
-
-			Convey("Casts an empty interface into a string slice", func() {
-				result, err := cast[[]string]([]string{"one", "two"})
-				So(result, ShouldResemble, []string{"one", "two"})
-				So(err, ShouldBeNil)
-			})
-
-			Convey("Returns an error if the cast can't be done", func() {
-				result, err := cast[[]string](5)
-				So(result, ShouldBeNil)
-				So(err, ShouldNotBeNil)
-			})
-		})
-
-		Convey("Casts strings", func() {
-
-			Convey("Casts an empty interface into a string", func() {
-				result, err := cast[string]("one")
-				So(result, ShouldEqual, "one")
-				So(err, ShouldBeNil)
-			})
-
-			Convey("Returns an error if the cast can't be done", func() {
-				result, err := cast[string](5)
-				So(result, ShouldBeZeroValue)
-				So(err, ShouldNotBeNil)
-			})
-		})
-
-		Convey("Casts boolean slices", func() {
-
-			Convey("Casts an empty interface into a boolean slice", func() {
-				result, err := cast[[]bool]([]bool{true, false})
-				So(result, ShouldResemble, []bool{true, false})
-				So(err, ShouldBeNil)
-			})
-
-			Convey("Returns an error if the cast can't be done", func() {
-				result, err := cast[[]bool](5)
-				So(result, ShouldBeNil)
-				So(err, ShouldNotBeNil)
-			})
-		})
-
-		Convey("Casts booleans", func() {
-
-			Convey("Casts an empty interface into a boolean", func() {
-				result, err := cast[bool](true)
-				So(result, ShouldEqual, true)
-				So(err, ShouldBeNil)
-			})
-
-			Convey("Returns an error if the cast can't be done", func() {
-				result, err := cast[bool](5)
-				So(result, ShouldBeZeroValue)
-				So(err, ShouldNotBeNil)
-			})
-		})
-
-		Convey("Casts float slices", func() {
-
-			Convey("Casts an empty interface into a float slice", func() {
-				result, err := cast[[]float64]([]float64{1, 2})
-				So(result, ShouldResemble, []float64{1, 2})
-				So(err, ShouldBeNil)
-			})
-
-			Convey("Returns an error if the cast can't be done", func() {
-				result, err := cast[[]float64](5)
-				So(result, ShouldBeNil)
-				So(err, ShouldNotBeNil)
-			})
-		})
-
-		Convey("Casts floats", func() {
-
-			Convey("Casts an empty interface into a float", func() {
-				result, err := cast[float64](3.3)
-				So(result, ShouldEqual, 3.3)
-				So(err, ShouldBeNil)
-			})
-
-			Convey("Returns an error if the cast can't be done", func() {
-				result, err := cast[float64]("Hello")
-				So(result, ShouldBeZeroValue)
-				So(err, ShouldNotBeNil)
-			})
-		})
-	})
-
-	Convey("castIntegerSlice", t, func() {
-
-		Convey("Casts an empty interface into a integer slice", func() {
-			result, err := castIntegerSlice([]int{1, 2})
-			So(result, ShouldResemble, []int{1, 2})
-			So(err, ShouldBeNil)
-		})
-
-		Convey("Attempts to cast a float slice to an int", func() {
-			result, err := castIntegerSlice([]float64{1, 2})
-			So(result, ShouldResemble, []int{1, 2})
-			So(err, ShouldBeNil)
-		})
-
-		Convey("Returns an error when casting a float slice to an int slice would truncate a value", func() {
-			result, err := castIntegerSlice([]float64{1.5, 2})
-			So(result, ShouldBeNil)
-			So(err, ShouldNotBeNil)
-		})
-
-		Convey("Returns an error if the cast can't be done", func() {
-			result, err := castIntegerSlice(5)
-			So(result, ShouldBeNil)
-			So(err, ShouldNotBeNil)
-		})
-	})
-
-	Convey("castInteger", t, func() {
-
-		Convey("Casts an empty interface into a integer", func() {
-			result, err := castInteger(1)
-			So(result, ShouldEqual, 1)
-			So(err, ShouldBeNil)
-		})
-
-		Convey("Attempts to cast a float to an int", func() {
-			result, err := castInteger(float64(1))
-			So(result, ShouldEqual, 1)
-			So(err, ShouldBeNil)
-		})
-
-		Convey("Returns an error when a converting to a int would truncate the value", func() {
-			result, err := castInteger(1.5)
-			So(result, ShouldBeZeroValue)
-			So(err, ShouldNotBeNil)
-		})
-
-		Convey("Returns an error if the cast can't be done", func() {
-			result, err := castInteger("Hello")
-			So(result, ShouldBeZeroValue)
-			So(err, ShouldNotBeNil)
-		})
-	})
-}
@@ -0,0 +1,328 @@
+package internal
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValidationError describes a structure field whose value breaks one of the rules in its `validate` tag
+type ValidationError struct {
+	Key     string
+	Value   interface{}
+	Source  *Source
+	Rule    string
+	Message string
+}
+
+// Error formats the validation error, including the key, the source of its value and the broken rule
+func (err *ValidationError) Error() string {
+	if err.Source == nil {
+		return fmt.Sprintf("key '%s' %s", err.Key, err.Message)
+	}
+	return fmt.Sprintf("key '%s' from %s %s", err.Key, err.Source, err.Message)
+}
+
+// ValidationErrors defines a collection of validation failures
+type ValidationErrors []*ValidationError
+
+// Error formats all the validation errors into a single message
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validationRule defines a single rule from a `validate` tag, such as `min=1`
+type validationRule struct {
+	name      string
+	parameter string
+}
+
+// validator collects the validation errors for a structure decoded from a configuration state
+type validator struct {
+	state     *state
+	delimiter string
+	errors    ValidationErrors
+}
+
+// validate checks the structure decoded from the value at the supplied keys against the rules in its `validate` tags,
+// returning ValidationErrors that lists every broken rule
+func validate(currentState *state, keys []string, delimiter string, structure interface{}) error {
+	checker := &validator{
+		state:     currentState,
+		delimiter: delimiter,
+	}
+	checker.value(reflect.ValueOf(structure), keys)
+
+	if len(checker.errors) > 0 {
+		return checker.errors
+	}
+	return nil
+}
+
+// value validates the structures in the supplied value, which is found at the supplied keys
+func (checker *validator) value(value reflect.Value, keys []string) {
+	value = indirect(value)
+	if !value.IsValid() || !containsStructure(value.Type()) {
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		checker.structure(value, keys)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			checker.value(value.Index(i), append(keys[:len(keys):len(keys)], strconv.Itoa(i)))
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			checker.value(value.MapIndex(key), append(keys[:len(keys):len(keys)], fmt.Sprint(key.Interface())))
+		}
+	}
+}
+
+// structure validates every field in the supplied structure against its rules
+func (checker *validator) structure(value reflect.Value, keys []string) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, squash, skip := fieldKey(field)
+		if skip || (!field.IsExported() && !squash) {
+			continue
+		}
+
+		fieldKeys := append(keys[:len(keys):len(keys)], checker.configKey(keys, name))
+		if squash {
+			fieldKeys = keys
+		}
+
+		for _, rule := range parseRules(field.Tag.Get("validate")) {
+			checker.rule(value.Field(i), fieldKeys, rule)
+		}
+		checker.value(value.Field(i), fieldKeys)
+	}
+}
+
+// configKey returns the key in the configuration that the field with the supplied name below the supplied keys was
+// decoded from, matching it case-insensitively the way mapstructure does. The field name is returned if the key doesn't
+// exist
+func (checker *validator) configKey(keys []string, name string) string {
+	parent, err := getPath(checker.state.values, keys, true)
+	if err != nil {
+		return name
+	}
+
+	parentMap, isMap := parent.(map[string]interface{})
+	if !isMap {
+		return name
+	}
+
+	existingKey, found := findKey(parentMap, name, true)
+	if !found {
+		return name
+	}
+	return existingKey
+}
+
+// rule checks a single rule against the value of a field, recording an error if it's broken
+func (checker *validator) rule(value reflect.Value, keys []string, rule validationRule) {
+	value = indirect(value)
+
+	var message string
+	switch rule.name {
+	case "required":
+		if !value.IsValid() || value.IsZero() || (hasLength(value) && value.Len() == 0) {
+			message = "is required"
+		}
+	case "min", "max":
+		if value.IsValid() {
+			message = checkLimit(value, rule)
+		}
+	case "oneof", "regex", "url", "hostport", "file-exists":
+
+		// Formats apply to every element of a slice, and only to values that are set
+		if value.IsValid() && (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) {
+			for i := 0; i < value.Len(); i++ {
+				checker.rule(value.Index(i), append(keys[:len(keys):len(keys)], strconv.Itoa(i)), rule)
+			}
+			return
+		}
+		if value.IsValid() && !value.IsZero() {
+			message = checkFormat(value, rule)
+		}
+	default:
+		message = fmt.Sprintf("has an unknown validation rule '%s'", rule.name)
+	}
+
+	if len(message) == 0 {
+		return
+	}
+
+	var fieldValue interface{}
+	if value.IsValid() {
+		fieldValue = value.Interface()
+	}
+
+	validationError := &ValidationError{
+		Key:     joinKey(keys, checker.delimiter),
+		Value:   fieldValue,
+		Rule:    rule.name,
+		Message: message,
+	}
+	source, found := checker.state.sourceOf(keys, true)
+	if found {
+		validationError.Source = &source
+	}
+	checker.errors = append(checker.errors, validationError)
+}
+
+// checkLimit checks a min or max rule, comparing numbers by value and strings, slices and maps by length. Returns a
+// message describing the failure, or an empty string if the value is within the limit
+func checkLimit(value reflect.Value, rule validationRule) string {
+	bound := "least"
+	if rule.name == "max" {
+		bound = "most"
+	}
+
+	var comparison int
+	var description string
+	if hasLength(value) {
+		limit, err := strconv.Atoi(rule.parameter)
+		if err != nil {
+			return fmt.Sprintf("has an invalid %s rule '%s'", rule.name, rule.parameter)
+		}
+		comparison = compare(value.Len(), limit)
+		description = fmt.Sprintf("must have a length of at %s %d, got %d", bound, limit, value.Len())
+	} else {
+		limit, err := coerceValue(rule.parameter, value.Type())
+		if err != nil {
+			return fmt.Sprintf("has an invalid %s rule '%s'", rule.name, rule.parameter)
+		}
+
+		switch value.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			comparison = compare(value.Int(), limit.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			comparison = compare(value.Uint(), limit.Uint())
+		case reflect.Float32, reflect.Float64:
+			comparison = compare(value.Float(), limit.Float())
+		default:
+			return fmt.Sprintf("has a %s rule, which doesn't apply to %s", rule.name, value.Type())
+		}
+		description = fmt.Sprintf("must be at %s %v, got %v", bound, limit.Interface(), value.Interface())
+	}
+
+	if (rule.name == "min" && comparison < 0) || (rule.name == "max" && comparison > 0) {
+		return description
+	}
+	return ""
+}
+
+// checkFormat checks a oneof, regex, url, hostport or file-exists rule. Returns a message describing the failure, which
+// leaves out the value since it may be a secret, or an empty string if the value matches
+func checkFormat(value reflect.Value, rule validationRule) string {
+	if rule.name == "oneof" {
+		options := strings.Fields(rule.parameter)
+		formatted := fmt.Sprint(value.Interface())
+		for _, option := range options {
+			if option == formatted {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(options, ", "))
+	}
+
+	if value.Kind() != reflect.String {
+		return fmt.Sprintf("has a %s rule, which doesn't apply to %s", rule.name, value.Type())
+	}
+	stringValue := value.String()
+
+	switch rule.name {
+	case "regex":
+		expression, err := regexp.Compile(rule.parameter)
+		if err != nil {
+			return fmt.Sprintf("has an invalid regex rule: %v", err)
+		}
+		if !expression.MatchString(stringValue) {
+			return fmt.Sprintf("must match '%s'", rule.parameter)
+		}
+	case "url":
+		parsed, err := url.Parse(stringValue)
+		if err != nil || len(parsed.Scheme) == 0 || len(parsed.Host) == 0 {
+			return "must be a URL with a scheme and host"
+		}
+	case "hostport":
+		host, port, err := net.SplitHostPort(stringValue)
+		portNumber, portErr := strconv.ParseUint(port, 10, 16)
+		if err != nil || portErr != nil || len(host) == 0 || portNumber == 0 {
+			return "must be a host and port"
+		}
+	case "file-exists":
+		_, err := os.Stat(stringValue)
+		if err != nil {
+			return "must be an existing file"
+		}
+	}
+
+	return ""
+}
+
+// parseRules splits a `validate` tag into its rules. Rules are separated with commas, which can be included in a
+// parameter by escaping them as `\,`, and parameters follow an equals sign
+func parseRules(tag string) []validationRule {
+	var rules []validationRule
+	part := strings.Builder{}
+
+	for i := 0; i <= len(tag); i++ {
+		switch {
+		case i < len(tag) && strings.HasPrefix(tag[i:], `\,`):
+			part.WriteByte(',')
+			i++
+		case i < len(tag) && tag[i] != ',':
+			part.WriteByte(tag[i])
+		case part.Len() > 0:
+			name, parameter, _ := strings.Cut(part.String(), "=")
+			rules = append(rules, validationRule{name: name, parameter: parameter})
+			part.Reset()
+		}
+	}
+
+	return rules
+}
+
+// indirect follows pointers and interfaces to the value they point at, returning an invalid value for nil
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) {
+		value = value.Elem()
+	}
+	return value
+}
+
+// hasLength determines if the supplied value is compared by length rather than by value
+func hasLength(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+// compare returns -1, 0 or 1 depending on whether a is less than, equal to or greater than b
+func compare[T int | int64 | uint64 | float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
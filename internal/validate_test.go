@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type validateServer struct {
+	Host string `mapstructure:"host" validate:"required"`
+	Port int    `mapstructure:"port" validate:"min=1,max=65535"`
+}
+
+type validateStructure struct {
+	Name      string           `mapstructure:"name" validate:"required,min=3,max=8"`
+	Level     string           `mapstructure:"level" validate:"oneof=debug info warn"`
+	Version   string           `mapstructure:"version" validate:"regex=^v[0-9]{1\\,3}$"`
+	Upstream  string           `mapstructure:"upstream" validate:"url"`
+	Listen    string           `mapstructure:"listen" validate:"hostport"`
+	Peers     []string         `mapstructure:"peers" validate:"min=1,hostport"`
+	CertFile  string           `mapstructure:"certFile" validate:"file-exists"`
+	Timeout   time.Duration    `mapstructure:"timeout" validate:"min=1s,max=1m"`
+	Ratio     *float64         `mapstructure:"ratio" validate:"required,max=1"`
+	Primary   validateServer   `mapstructure:"primary"`
+	Replicas  []validateServer `mapstructure:"replicas"`
+	Unchecked string           `mapstructure:"unchecked"`
+}
+
+func TestValidate(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "cert.pem")
+	err := os.WriteFile(certFile, []byte("cert"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"name":     "service",
+			"level":    "info",
+			"version":  "v12",
+			"upstream": "http://upstream:8080/path",
+			"listen":   "localhost:80",
+			"peers":    []interface{}{"a:1", "b:2"},
+			"certFile": certFile,
+			"timeout":  "30s",
+			"ratio":    0.5,
+			"primary":  map[string]interface{}{"host": "db", "port": 5432},
+			"replicas": []interface{}{map[string]interface{}{"host": "replica", "port": 5433}},
+		}
+	}
+
+	Convey("Accepts a structure that follows every rule", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(valid()))
+		So(config.ToStructure(&validateStructure{}), ShouldBeNil)
+	})
+
+	Convey("Returns every broken rule with its key and source", t, func() {
+		values := valid()
+		values["name"] = "a-very-long-name"
+		values["level"] = "verbose"
+		values["version"] = "v1234"
+		values["upstream"] = "upstream"
+		values["listen"] = "localhost"
+		values["peers"] = []interface{}{"a:1", "b"}
+		values["certFile"] = certFile + ".missing"
+		values["timeout"] = "2m"
+		values["ratio"] = 1.5
+		values["primary"] = map[string]interface{}{"port": 0}
+		values["replicas"] = []interface{}{map[string]interface{}{"host": "replica", "port": 70000}}
+
+		config := NewConfig()
+		config.Use(NewMapLoader(values))
+		err := config.ToStructure(&validateStructure{})
+
+		So(err, ShouldHaveSameTypeAs, ValidationErrors{})
+		errs := err.(ValidationErrors)
+		keys := make([]string, len(errs))
+		for i, validationError := range errs {
+			keys[i] = validationError.Key
+		}
+		So(keys, ShouldResemble, []string{
+			"name", "level", "version", "upstream", "listen", "peers:1", "certFile", "timeout", "ratio",
+			"primary:host", "primary:port", "replicas:0:port",
+		})
+
+		So(errs[0].Rule, ShouldEqual, "max")
+		So(errs[0].Value, ShouldEqual, "a-very-long-name")
+		So(errs[0].Error(), ShouldEqual, "key 'name' from map (position 0) must have a length of at most 8, got 16")
+		So(errs[1].Error(), ShouldEqual, "key 'level' from map (position 0) must be one of debug, info, warn")
+		So(errs[7].Error(), ShouldEqual, "key 'timeout' from map (position 0) must be at most 1m0s, got 2m0s")
+		So(errs[9].Error(), ShouldEqual, "key 'primary:host' is required")
+		So(errs[9].Source, ShouldBeNil)
+		So(err.Error(), ShouldContainSubstring, "; key 'level' from map (position 0)")
+	})
+
+	Convey("Only checks formats for values that are set", t, func() {
+		values := valid()
+		delete(values, "level")
+		delete(values, "upstream")
+		delete(values, "certFile")
+
+		config := NewConfig()
+		config.Use(NewMapLoader(values))
+		So(config.ToStructure(&validateStructure{}), ShouldBeNil)
+	})
+
+	Convey("Checks the lengths of slices", t, func() {
+		values := valid()
+		values["peers"] = []interface{}{}
+
+		config := NewConfig()
+		config.Use(NewMapLoader(values))
+		err := config.ToStructure(&validateStructure{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "key 'peers' from map (position 0) must have a length of at least 1, got 0")
+	})
+
+	Convey("Checks defaults and reports keys with the configured delimiter", t, func() {
+		structure := struct {
+			Database struct {
+				Port int `default:"0" validate:"min=1"`
+			}
+		}{}
+
+		config := NewConfig(WithDelimiter("."))
+		err := config.ToStructure(&structure)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "key 'Database.Port' must be at least 1, got 0")
+	})
+
+	Convey("Finds sources for keys that differ in case from the field", t, func() {
+		structure := struct {
+			Port int `mapstructure:"port" validate:"min=1"`
+		}{}
+
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"PORT": 0}))
+		err := config.ToStructure(&structure)
+		So(err, ShouldNotBeNil)
+		So(err.(ValidationErrors)[0].Source, ShouldResemble, &Source{Kind: "map", Index: 0})
+	})
+
+	Convey("Reports the configuration keys of fields without mapstructure tags", t, func() {
+		structure := struct {
+			DB struct {
+				Port     int `validate:"min=1"`
+				Replicas []struct {
+					Host string `validate:"required"`
+				}
+			}
+			Name string `validate:"required"`
+		}{}
+
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{
+			"db": map[string]interface{}{"port": 0, "replicas": []interface{}{map[string]interface{}{"host": ""}}},
+		}))
+		err := config.ToStructure(&structure)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "key 'db:port' from map (position 0) must be at least 1, got 0; "+
+			"key 'db:replicas:0:host' from map (position 0) is required; key 'Name' is required")
+	})
+
+	Convey("Reports unknown and invalid rules", t, func() {
+		structure := struct {
+			Name  string `validate:"unique"`
+			Count int    `validate:"min=many"`
+			Host  string `validate:"regex=["`
+		}{Host: "a"}
+
+		err := validate(&state{}, []string{}, defaultDelimiter, &structure)
+		So(err, ShouldNotBeNil)
+		So(err.(ValidationErrors), ShouldHaveLength, 3)
+		So(err.Error(), ShouldContainSubstring, "key 'Name' has an unknown validation rule 'unique'")
+		So(err.Error(), ShouldContainSubstring, "key 'Count' has an invalid min rule 'many'")
+		So(err.Error(), ShouldContainSubstring, "key 'Host' has an invalid regex rule")
+	})
+
+	Convey("Doesn't validate maps", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"key": "value"}))
+		So(config.ToStructure(&map[string]interface{}{}), ShouldBeNil)
+	})
+}
+
+func TestParseRules(t *testing.T) {
+
+	Convey("Splits rules and their parameters", t, func() {
+		So(parseRules("required,min=1,oneof=a b"), ShouldResemble, []validationRule{
+			{name: "required"},
+			{name: "min", parameter: "1"},
+			{name: "oneof", parameter: "a b"},
+		})
+	})
+
+	Convey("Keeps escaped commas and other backslashes in parameters", t, func() {
+		So(parseRules(`regex=^\d{1\,3}$`), ShouldResemble, []validationRule{{name: "regex", parameter: `^\d{1,3}$`}})
+	})
+
+	Convey("Ignores empty rules", t, func() {
+		So(parseRules(""), ShouldBeEmpty)
+		So(parseRules("required,,"), ShouldResemble, []validationRule{{name: "required"}})
+	})
+}
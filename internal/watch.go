@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultWatchInterval is the polling interval used when Watch is given an interval that isn't positive
+const defaultWatchInterval = time.Second
+
+// Watcher defines a poller that reloads a configuration whenever one of its files changes on disk
+type Watcher struct {
+	config       *Config
+	interval     time.Duration
+	onReload     func(error)
+	fingerprints map[string]string
+	stop         chan struct{}
+	done         chan struct{}
+	stopOnce     sync.Once
+}
+
+// Watch starts watching every JSON and YAML file in the loading chain, reloading the whole chain when one of them
+// changes. The optional onReload callback is called after every reload with the reload error, if any. An interval
+// that isn't positive polls every second
+func (config *Config) Watch(interval time.Duration, onReload func(error)) *Watcher {
+	watcher := newWatcher(config, interval, onReload)
+	go watcher.run()
+	return watcher
+}
+
+// newWatcher creates a new watcher, fingerprinting the currently watched files
+func newWatcher(config *Config, interval time.Duration, onReload func(error)) *Watcher {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	watcher := &Watcher{
+		config:   config,
+		interval: interval,
+		onReload: onReload,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	watcher.fingerprints = watcher.fingerprint()
+	return watcher
+}
+
+// Stop stops watching for changes, waiting for any in-progress reload to finish
+func (watcher *Watcher) Stop() {
+	watcher.stopOnce.Do(func() {
+		close(watcher.stop)
+	})
+	<-watcher.done
+}
+
+// run polls the watched files until the watcher is stopped
+func (watcher *Watcher) run() {
+	defer close(watcher.done)
+
+	ticker := time.NewTicker(watcher.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-watcher.stop:
+			return
+		case <-ticker.C:
+			watcher.poll()
+		}
+	}
+}
+
+// poll checks the watched files for changes, reloading the configuration if any of them changed. Returns true if a
+// reload was attempted
+func (watcher *Watcher) poll() bool {
+	fingerprints := watcher.fingerprint()
+	if equalFingerprints(watcher.fingerprints, fingerprints) {
+		return false
+	}
+
+	// Only remember the new fingerprints if the reload worked, so that a half-written file is retried next time
+	err := watcher.config.Reload()
+	if err == nil {
+		watcher.fingerprints = fingerprints
+	}
+
+	if watcher.onReload != nil {
+		watcher.onReload(err)
+	}
+	return true
+}
+
+// fingerprint computes a fingerprint of the contents of every watched file. Hashing the contents (rather than relying
+// on modification times) means files replaced through a rename or a symlink swap are picked up as well
+func (watcher *Watcher) fingerprint() map[string]string {
+	fingerprints := map[string]string{}
+	for _, filePath := range watchedFiles(watcher.config) {
+		contents, err := os.ReadFile(filePath)
+		if err != nil {
+			fingerprints[filePath] = "error: " + err.Error()
+			continue
+		}
+		sum := sha256.Sum256(contents)
+		fingerprints[filePath] = string(sum[:])
+	}
+	return fingerprints
+}
+
+// watchedFiles returns the path of every file loaded by the configuration's loader chain. Sub configs can't be
+// reloaded, so they don't watch any files
+func watchedFiles(config *Config) []string {
+	if config.sub {
+		return nil
+	}
+
+	var files []string
+	for _, configLayer := range config.current().layers {
+		filePath := loaderFile(configLayer.loader)
+		if len(filePath) > 0 {
+			files = append(files, filePath)
+		}
+	}
+	return files
+}
+
+// loaderFile returns the file loaded by the supplied loader, or an empty string if it doesn't load a file
+func loaderFile(loader Loader) string {
+	switch typedLoader := loader.(type) {
+	case *JSONFileLoader:
+		return typedLoader.filePath
+	case *YAMLFileLoader:
+		return typedLoader.filePath
+	case *OptionalLoader:
+		return loaderFile(typedLoader.loader)
+	case *DecryptingLoader:
+		return loaderFile(typedLoader.loader)
+	default:
+		return ""
+	}
+}
+
+// equalFingerprints determines if two sets of file fingerprints are the same
+func equalFingerprints(fingerprints1 map[string]string, fingerprints2 map[string]string) bool {
+	if len(fingerprints1) != len(fingerprints2) {
+		return false
+	}
+
+	for filePath, fingerprint := range fingerprints1 {
+		if fingerprints2[filePath] != fingerprint {
+			return false
+		}
+	}
+	return true
+}
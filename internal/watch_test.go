@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReload(t *testing.T) {
+
+	Convey("Re-runs the loader chain in its original order", t, func() {
+		path := filepath.Join(t.TempDir(), "config.json")
+		So(os.WriteFile(path, []byte(`{"one": 1, "two": 2}`), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"one": "map"}))
+		config.Use(NewJSONFileLoader(path, false))
+		So(config.Set("three", 3), ShouldBeNil)
+
+		So(os.WriteFile(path, []byte(`{"one": 1, "two": 22}`), 0644), ShouldBeNil)
+		So(config.Reload(), ShouldBeNil)
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": "map", "two": 22.0, "three": 3})
+
+		source, err := config.Source("two")
+		So(source.Index, ShouldEqual, 1)
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Discards values set in slices that shrank", t, func() {
+		path := filepath.Join(t.TempDir(), "config.json")
+		So(os.WriteFile(path, []byte(`{"servers": ["a", "b"]}`), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewJSONFileLoader(path, false))
+		So(config.Set("servers:2", "c"), ShouldBeNil)
+
+		So(os.WriteFile(path, []byte(`{"servers": ["a"]}`), 0644), ShouldBeNil)
+		So(config.Reload(), ShouldBeNil)
+		So(config.Map(), ShouldResemble, map[string]interface{}{"servers": []interface{}{"a"}})
+
+		// The discarded value isn't written again when the slice grows back
+		So(os.WriteFile(path, []byte(`{"servers": ["a", "b"]}`), 0644), ShouldBeNil)
+		So(config.Reload(), ShouldBeNil)
+		So(config.Map(), ShouldResemble, map[string]interface{}{"servers": []interface{}{"a", "b"}})
+
+		source, err := config.Source("servers")
+		So(source.Kind, ShouldEqual, "JSON file")
+		So(err, ShouldBeNil)
+	})
+
+	Convey("Keeps the existing configuration when a loader fails", t, func() {
+		path := filepath.Join(t.TempDir(), "config.json")
+		So(os.WriteFile(path, []byte(`{"one": 1}`), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewJSONFileLoader(path, false))
+
+		So(os.WriteFile(path, []byte(`{`), 0644), ShouldBeNil)
+		err := config.Reload()
+		So(err, ShouldHaveSameTypeAs, LoaderErrors{})
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 1.0})
+	})
+
+	Convey("Refuses to reload sub configs, even after a value was set", t, func() {
+		path := filepath.Join(t.TempDir(), "config.json")
+		So(os.WriteFile(path, []byte(`{"db": {"host": "localhost"}, "port": 80}`), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewJSONFileLoader(path, false))
+		So(config.Set("db:user", "admin"), ShouldBeNil)
+
+		sub, err := config.GetSubConfig("db")
+		So(err, ShouldBeNil)
+		So(sub.Reload(), ShouldEqual, errSubConfigReload)
+		So(sub.Map(), ShouldResemble, map[string]interface{}{"host": "localhost", "user": "admin"})
+
+		source, err := sub.Source("user")
+		So(source, ShouldResemble, setSource)
+		So(err, ShouldBeNil)
+	})
+}
+
+func TestWatcher(t *testing.T) {
+
+	Convey("Doesn't reload when nothing changed", t, func() {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		So(os.WriteFile(path, []byte("one: 1"), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewYAMLFileLoader(path, false))
+
+		watcher := newWatcher(config, time.Hour, nil)
+		So(watcher.poll(), ShouldBeFalse)
+	})
+
+	Convey("Doesn't watch the files of sub configs", t, func() {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		So(os.WriteFile(path, []byte("db:\n  host: localhost"), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewYAMLFileLoader(path, false))
+		sub, err := config.GetSubConfig("db")
+		So(err, ShouldBeNil)
+		watcher := newWatcher(sub, time.Hour, nil)
+
+		So(os.WriteFile(path, []byte("db:\n  host: example.com"), 0644), ShouldBeNil)
+		So(watcher.poll(), ShouldBeFalse)
+		So(sub.Map(), ShouldResemble, map[string]interface{}{"host": "localhost"})
+	})
+
+	Convey("Reloads when a file is written in place", t, func() {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		So(os.WriteFile(path, []byte("one: 1"), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewYAMLFileLoader(path, false))
+		watcher := newWatcher(config, time.Hour, nil)
+
+		So(os.WriteFile(path, []byte("one: 2"), 0644), ShouldBeNil)
+		So(watcher.poll(), ShouldBeTrue)
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 2})
+		So(watcher.poll(), ShouldBeFalse)
+	})
+
+	Convey("Reloads when a new file is renamed over the old one", t, func() {
+		directory := t.TempDir()
+		path := filepath.Join(directory, "config.yaml")
+		So(os.WriteFile(path, []byte("one: 1"), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewYAMLFileLoader(path, false))
+		watcher := newWatcher(config, time.Hour, nil)
+
+		temporaryPath := filepath.Join(directory, ".config.yaml.swp")
+		So(os.WriteFile(temporaryPath, []byte("one: 2"), 0644), ShouldBeNil)
+		So(os.Rename(temporaryPath, path), ShouldBeNil)
+
+		So(watcher.poll(), ShouldBeTrue)
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 2})
+	})
+
+	Convey("Reloads when a symlinked directory is swapped like a Kubernetes ConfigMap", t, func() {
+		directory := t.TempDir()
+		So(os.Mkdir(filepath.Join(directory, "..v1"), 0755), ShouldBeNil)
+		So(os.WriteFile(filepath.Join(directory, "..v1", "config.json"), []byte(`{"one": 1}`), 0644), ShouldBeNil)
+		So(os.Symlink("..v1", filepath.Join(directory, "..data")), ShouldBeNil)
+		So(os.Symlink(filepath.Join("..data", "config.json"), filepath.Join(directory, "config.json")), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewJSONFileLoader(filepath.Join(directory, "config.json"), false))
+		watcher := newWatcher(config, time.Hour, nil)
+
+		// Write the new version and atomically swap the data link over to it
+		So(os.Mkdir(filepath.Join(directory, "..v2"), 0755), ShouldBeNil)
+		So(os.WriteFile(filepath.Join(directory, "..v2", "config.json"), []byte(`{"one": 2}`), 0644), ShouldBeNil)
+		So(os.Symlink("..v2", filepath.Join(directory, "..data_tmp")), ShouldBeNil)
+		So(os.Rename(filepath.Join(directory, "..data_tmp"), filepath.Join(directory, "..data")), ShouldBeNil)
+
+		So(watcher.poll(), ShouldBeTrue)
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 2.0})
+	})
+
+	Convey("Reloads when an optional file appears", t, func() {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+
+		config := NewConfig()
+		config.Use(NewYAMLFileLoader(path, false).Optional())
+		watcher := newWatcher(config, time.Hour, nil)
+
+		So(os.WriteFile(path, []byte("one: 1"), 0644), ShouldBeNil)
+		So(watcher.poll(), ShouldBeTrue)
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 1})
+	})
+
+	Convey("Reports reload failures and retries on the next poll", t, func() {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		So(os.WriteFile(path, []byte("one: 1"), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewYAMLFileLoader(path, false))
+
+		var reloadErrors []error
+		watcher := newWatcher(config, time.Hour, func(err error) {
+			reloadErrors = append(reloadErrors, err)
+		})
+
+		So(os.WriteFile(path, []byte("one: ["), 0644), ShouldBeNil)
+		So(watcher.poll(), ShouldBeTrue)
+		So(watcher.poll(), ShouldBeTrue)
+		So(reloadErrors, ShouldHaveLength, 2)
+		So(reloadErrors[0], ShouldNotBeNil)
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 1})
+	})
+
+	Convey("Reloads in the background until stopped", t, func() {
+		path := filepath.Join(t.TempDir(), "config.json")
+		So(os.WriteFile(path, []byte(`{"one": 1}`), 0644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewJSONFileLoader(path, false))
+
+		reloaded := make(chan error, 1)
+		watcher := config.Watch(5*time.Millisecond, func(err error) {
+			reloaded <- err
+		})
+		defer watcher.Stop()
+
+		So(os.WriteFile(path, []byte(`{"one": 2}`), 0644), ShouldBeNil)
+
+		select {
+		case err := <-reloaded:
+			So(err, ShouldBeNil)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a reload")
+		}
+
+		watcher.Stop()
+		So(config.Map(), ShouldResemble, map[string]interface{}{"one": 2.0})
+	})
+
+	Convey("Falls back to the default interval for intervals that aren't positive", t, func() {
+		config := NewConfig()
+		So(newWatcher(config, 0, nil).interval, ShouldEqual, defaultWatchInterval)
+		So(newWatcher(config, -time.Second, nil).interval, ShouldEqual, defaultWatchInterval)
+
+		watcher := config.Watch(0, nil)
+		watcher.Stop()
+	})
+}
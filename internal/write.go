@@ -0,0 +1,251 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format defines a format a configuration can be written in
+type Format string
+
+// The formats a configuration can be written in
+const (
+	FormatJSON       Format = "json"
+	FormatYAML       Format = "yaml"
+	FormatTOML       Format = "toml"
+	FormatDotenv     Format = "env"
+	FormatProperties Format = "properties"
+)
+
+// dotenvSeparator separates the segments of the keys in .env files, matching the usual environment loader separator
+const dotenvSeparator = "__"
+
+// unquotedDotenvValue matches the .env values that can be written without quotes
+var unquotedDotenvValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,-]*$`)
+
+// Write writes the loaded configuration to the supplied writer as JSON, YAML, TOML, `.env` lines or `key=value` lines.
+// Keys are sorted, and durations are written as strings that the file loaders parse back when parseDurations is set.
+// References are written as they were loaded rather than interpolated, so the file loads back the same way
+func (config *Config) Write(writer io.Writer, format Format) error {
+	encoded, err := encode(PrintableValue(config.current().rawValues()).(map[string]interface{}), format, config.options.delimiter)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(encoded)
+	return err
+}
+
+// WriteFile writes the loaded configuration to a file in the supplied format, replacing the file if it exists
+func (config *Config) WriteFile(filePath string, format Format) error {
+	buffer := &bytes.Buffer{}
+	err := config.Write(buffer, format)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, buffer.Bytes(), 0o644)
+}
+
+// PrintableValue returns a copy of the supplied value with the durations and other fmt.Stringer values in it replaced by
+// their string forms, the way they're written to files and dumped. Times are left as they are
+func PrintableValue(value interface{}) interface{} {
+	return printableValue(value, []string{}, nil)
+}
+
+// printableValue returns a copy of the supplied value, found at the supplied keys, with durations and other
+// fmt.Stringer values replaced by their string forms. Every leaf is passed through the replace function first, if
+// there is one
+func printableValue(value interface{}, keys []string, replace func(interface{}, []string) interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(typedValue))
+		for key, element := range typedValue {
+			converted[key] = printableValue(element, append(keys[:len(keys):len(keys)], key), replace)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(typedValue))
+		for i, element := range typedValue {
+			converted[i] = printableValue(element, append(keys[:len(keys):len(keys)], strconv.Itoa(i)), replace)
+		}
+		return converted
+	}
+
+	if replace != nil {
+		value = replace(value, keys)
+	}
+
+	switch typedValue := value.(type) {
+	case time.Duration:
+		return typedValue.String()
+	case time.Time:
+		return value // Every format writes times in their RFC 3339 form, and YAML and TOML load them back as times
+	case fmt.Stringer:
+		return typedValue.String()
+	default:
+		return value
+	}
+}
+
+// encode formats a configuration map in the supplied format, with its keys sorted
+func encode(values map[string]interface{}, format Format, delimiter string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		encoded, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(encoded, '\n'), nil
+	case FormatYAML:
+		return yaml.Marshal(values)
+	case FormatTOML:
+		buffer := &bytes.Buffer{}
+		err := toml.NewEncoder(buffer).Encode(values)
+		return buffer.Bytes(), err
+	case FormatDotenv:
+		return dotenvLines(values)
+	case FormatProperties:
+		return propertyLines(values, delimiter), nil
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// dotenvLines formats a configuration map as `.env` lines, sorted by key. Nested keys are joined with `__` and
+// upper-cased, and slices are written as JSON, which the environment loader parses back
+func dotenvLines(values map[string]interface{}) ([]byte, error) {
+	lines := map[string]string{}
+	err := addDotenvLines(lines, values, []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	return sortedLines(lines), nil
+}
+
+// addDotenvLines adds a line for every leaf in the supplied value to the lines map
+func addDotenvLines(lines map[string]string, value interface{}, keys []string) error {
+	name := strings.ToUpper(strings.Join(keys, dotenvSeparator))
+
+	var formatted string
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		if len(typedValue) > 0 || len(keys) == 0 {
+			for key, element := range typedValue {
+				err := addDotenvLines(lines, element, append(keys[:len(keys):len(keys)], key))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		formatted = "{}"
+	case []interface{}:
+		encoded, err := json.Marshal(typedValue)
+		if err != nil {
+			return fmt.Errorf("failed to write key '%s': %w", name, err)
+		}
+		formatted = string(encoded)
+	case time.Time:
+		formatted = typedValue.Format(time.RFC3339Nano)
+	case nil:
+		formatted = ""
+	default:
+		formatted = fmt.Sprint(typedValue)
+	}
+
+	_, exists := lines[name]
+	if exists {
+		return fmt.Errorf("keys that only differ in case can't be written as environment variables: '%s'", name)
+	}
+	lines[name] = quoteDotenvValue(formatted)
+	return nil
+}
+
+// quoteDotenvValue quotes a .env value if it contains anything other than simple characters. Single quotes keep the
+// value literal, and double quotes are used for values that contain single quotes or line breaks
+func quoteDotenvValue(value string) string {
+	switch {
+	case unquotedDotenvValue.MatchString(value):
+		return value
+	case !strings.ContainsAny(value, "'\n\r"):
+		return "'" + value + "'"
+	default:
+		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(value)
+		return `"` + escaped + `"`
+	}
+}
+
+// propertyLines formats a configuration map as `key=value` lines, sorted by key. Strings that wouldn't survive
+// being written on a single line are quoted
+func propertyLines(values map[string]interface{}, delimiter string) []byte {
+	lines := map[string]string{}
+	addPropertyLines(lines, values, []string{}, delimiter)
+	return sortedLines(lines)
+}
+
+// addPropertyLines adds a line for every leaf in the supplied value to the lines map
+func addPropertyLines(lines map[string]string, value interface{}, keys []string, delimiter string) {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		if len(typedValue) == 0 && len(keys) > 0 {
+			lines[joinKey(keys, delimiter)] = "{}"
+		}
+		for key, element := range typedValue {
+			addPropertyLines(lines, element, append(keys[:len(keys):len(keys)], key), delimiter)
+		}
+	case []interface{}:
+		if len(typedValue) == 0 {
+			lines[joinKey(keys, delimiter)] = "[]"
+		}
+		for i, element := range typedValue {
+			addPropertyLines(lines, element, append(keys[:len(keys):len(keys)], strconv.Itoa(i)), delimiter)
+		}
+	default:
+		lines[joinKey(keys, delimiter)] = formatProperty(typedValue)
+	}
+}
+
+// formatProperty formats a leaf value for a `key=value` line, quoting strings that wouldn't survive being written on
+// a single line
+func formatProperty(value interface{}) string {
+	switch typedValue := value.(type) {
+	case string:
+		if strings.TrimSpace(typedValue) != typedValue || strconv.Quote(typedValue) != `"`+typedValue+`"` {
+			return strconv.Quote(typedValue)
+		}
+		return typedValue
+	case time.Time:
+		return typedValue.Format(time.RFC3339Nano)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(typedValue)
+	}
+}
+
+// sortedLines writes `key=value` lines for the supplied keys and formatted values, sorted by key
+func sortedLines(lines map[string]string) []byte {
+	keys := make([]string, 0, len(lines))
+	for key := range lines {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buffer := &bytes.Buffer{}
+	for _, key := range keys {
+		buffer.WriteString(key + "=" + lines[key] + "\n")
+	}
+	return buffer.Bytes()
+}
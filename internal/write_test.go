@@ -0,0 +1,217 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// failingWriter defines a writer that always fails
+type failingWriter struct {
+	err error
+}
+
+func (writer *failingWriter) Write([]byte) (int, error) {
+	return 0, writer.err
+}
+
+func TestWrite(t *testing.T) {
+	newWriteConfig := func() *Config {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{
+			"server": map[string]interface{}{
+				"host":    "localhost",
+				"port":    8080,
+				"timeout": 90 * time.Second,
+			},
+			"servers": []interface{}{
+				map[string]interface{}{"name": "a", "weight": 1.5},
+			},
+			"debug":    true,
+			"password": "it's \"secret\"",
+			"tags":     []interface{}{"one", "two"},
+		}))
+		return config
+	}
+
+	Convey("Writes JSON with sorted keys and durations as strings", t, func() {
+		buffer := &bytes.Buffer{}
+		So(newWriteConfig().Write(buffer, FormatJSON), ShouldBeNil)
+		So(buffer.String(), ShouldEqual, `{
+  "debug": true,
+  "password": "it's \"secret\"",
+  "server": {
+    "host": "localhost",
+    "port": 8080,
+    "timeout": "1m30s"
+  },
+  "servers": [
+    {
+      "name": "a",
+      "weight": 1.5
+    }
+  ],
+  "tags": [
+    "one",
+    "two"
+  ]
+}
+`)
+	})
+
+	Convey("Writes YAML", t, func() {
+		buffer := &bytes.Buffer{}
+		So(newWriteConfig().Write(buffer, FormatYAML), ShouldBeNil)
+		So(buffer.String(), ShouldEqual, `debug: true
+password: it's "secret"
+server:
+    host: localhost
+    port: 8080
+    timeout: 1m30s
+servers:
+    - name: a
+      weight: 1.5
+tags:
+    - one
+    - two
+`)
+	})
+
+	Convey("Writes TOML", t, func() {
+		buffer := &bytes.Buffer{}
+		So(newWriteConfig().Write(buffer, FormatTOML), ShouldBeNil)
+		So(buffer.String(), ShouldEqual, `debug = true
+password = "it's \"secret\""
+tags = ["one", "two"]
+
+[server]
+  host = "localhost"
+  port = 8080
+  timeout = "1m30s"
+
+[[servers]]
+  name = "a"
+  weight = 1.5
+`)
+
+		decoded := map[string]interface{}{}
+		_, err := toml.Decode(buffer.String(), &decoded)
+		So(err, ShouldBeNil)
+		So(decoded["server"], ShouldResemble, map[string]interface{}{"host": "localhost", "port": int64(8080), "timeout": "1m30s"})
+	})
+
+	Convey("Writes .env lines", t, func() {
+		buffer := &bytes.Buffer{}
+		So(newWriteConfig().Write(buffer, FormatDotenv), ShouldBeNil)
+		So(buffer.String(), ShouldEqual, `DEBUG=true
+PASSWORD="it's \"secret\""
+SERVERS='[{"name":"a","weight":1.5}]'
+SERVER__HOST=localhost
+SERVER__PORT=8080
+SERVER__TIMEOUT=1m30s
+TAGS='["one","two"]'
+`)
+	})
+
+	Convey("Writes .env lines that load back through the environment loader", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{
+			"server": map[string]interface{}{"port": 8080, "timeout": 90 * time.Second},
+			"tags":   []interface{}{"one", "two"},
+		}))
+
+		buffer := &bytes.Buffer{}
+		So(config.Write(buffer, FormatDotenv), ShouldBeNil)
+
+		environment := []string{}
+		for _, line := range bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n")) {
+			environment = append(environment, string(bytes.ReplaceAll(line, []byte("'"), nil)))
+		}
+		loaded, _, err := NewEnvironmentLoader(true, "__", "").parseEnvironment(environment)
+		So(err, ShouldBeNil)
+		So(loaded, ShouldResemble, config.Map())
+	})
+
+	Convey("Returns an error for keys that only differ in case in .env files", t, func() {
+		config := NewConfig()
+		config.Use(NewMapLoader(map[string]interface{}{"host": "a", "HOST": "b"}))
+		So(config.Write(&bytes.Buffer{}, FormatDotenv), ShouldNotBeNil)
+	})
+
+	Convey("Writes key=value lines", t, func() {
+		buffer := &bytes.Buffer{}
+		So(newWriteConfig().Write(buffer, FormatProperties), ShouldBeNil)
+		So(buffer.String(), ShouldEqual, `debug=true
+password="it's \"secret\""
+server:host=localhost
+server:port=8080
+server:timeout=1m30s
+servers:0:name=a
+servers:0:weight=1.5
+tags:0=one
+tags:1=two
+`)
+	})
+
+	Convey("Writes references as they were loaded instead of interpolating them", t, func() {
+		config := NewConfig(WithInterpolation())
+		config.Use(NewMapLoader(map[string]interface{}{"host": "localhost", "url": "http://${host}", "price": "$${amount}"}))
+		So(config.MustGetString("url"), ShouldEqual, "http://localhost")
+
+		buffer := &bytes.Buffer{}
+		So(config.Write(buffer, FormatProperties), ShouldBeNil)
+		So(buffer.String(), ShouldEqual, "host=localhost\nprice=$${amount}\nurl=http://${host}\n")
+	})
+
+	Convey("Returns errors for unsupported formats and failed writes", t, func() {
+		So(newWriteConfig().Write(&bytes.Buffer{}, "xml").Error(), ShouldEqual, "unsupported format 'xml'")
+
+		cause := errors.New("boom")
+		So(newWriteConfig().Write(&failingWriter{err: cause}, FormatJSON), ShouldEqual, cause)
+	})
+}
+
+func TestWriteFile(t *testing.T) {
+	directory := t.TempDir()
+
+	Convey("Writes JSON files that load back identically", t, func() {
+		source := filepath.Join(directory, "source.json")
+		So(os.WriteFile(source, []byte(`{"b": {"timeout": "1m30s", "port": 8080, "ratio": 0.5}, "a": [1, "two", null], "c": {}}`), 0o644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewJSONFileLoader(source, true))
+
+		written := filepath.Join(directory, "written.json")
+		So(config.WriteFile(written, FormatJSON), ShouldBeNil)
+
+		reloaded := NewConfig()
+		reloaded.Use(NewJSONFileLoader(written, true))
+		So(reloaded.Map(), ShouldResemble, config.Map())
+		So(reloaded.Map()["b"].(map[string]interface{})["timeout"], ShouldEqual, 90*time.Second)
+	})
+
+	Convey("Writes YAML files that load back identically", t, func() {
+		source := filepath.Join(directory, "source.yaml")
+		So(os.WriteFile(source, []byte("b:\n  timeout: 1m30s\n  port: 8080\n  ratio: 0.5\n  started: 2024-01-02T03:04:05Z\na: [1, two, null]\nc: {}\n"), 0o644), ShouldBeNil)
+
+		config := NewConfig()
+		config.Use(NewYAMLFileLoader(source, true))
+
+		written := filepath.Join(directory, "written.yaml")
+		So(config.WriteFile(written, FormatYAML), ShouldBeNil)
+
+		reloaded := NewConfig()
+		reloaded.Use(NewYAMLFileLoader(written, true))
+		So(reloaded.Map(), ShouldResemble, config.Map())
+	})
+
+	Convey("Returns an error if the file can't be written", t, func() {
+		So(NewConfig().WriteFile(filepath.Join(directory, "missing", "config.json"), FormatJSON), ShouldNotBeNil)
+	})
+}
@@ -9,6 +9,7 @@ import (
 type YAMLFileLoader struct {
 	filePath       string
 	parseDurations bool
+	optional       bool
 }
 
 // NewYAMLFileLoader creates a new YAML file loader
@@ -19,9 +20,18 @@ func NewYAMLFileLoader(filePath string, parseDurations bool) *YAMLFileLoader {
 	}
 }
 
+// Optional marks the file as optional, so a missing file loads as an empty configuration
+func (loader *YAMLFileLoader) Optional() *YAMLFileLoader {
+	loader.optional = true
+	return loader
+}
+
 // Load loads a YAML file
 func (loader *YAMLFileLoader) Load() (map[string]interface{}, error) {
 	file, err := os.ReadFile(loader.filePath)
+	if loader.optional && isNotExist(err) {
+		return map[string]interface{}{}, nil
+	}
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
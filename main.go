@@ -2,15 +2,25 @@ package gconf
 
 import (
 	"github.com/miratronix/gconf/internal"
+	"io/fs"
 	"sync"
 )
 
 var configSingleton *internal.Config
 var once sync.Once
 
+// The formats a configuration can be written in
+const (
+	FormatJSON       = internal.FormatJSON
+	FormatYAML       = internal.FormatYAML
+	FormatTOML       = internal.FormatTOML
+	FormatDotenv     = internal.FormatDotenv
+	FormatProperties = internal.FormatProperties
+)
+
 // New creates a new configuration structure
-func New() *internal.Config {
-	return internal.NewConfig()
+func New(options ...internal.Option) *internal.Config {
+	return internal.NewConfig(options...)
 }
 
 // Instance returns a singleton configuration structure instance
@@ -21,6 +31,183 @@ func Instance() *internal.Config {
 	return configSingleton
 }
 
+// LoaderError describes a loader that failed to load
+type LoaderError = internal.LoaderError
+
+// LoaderErrors describes a collection of loader failures
+type LoaderErrors = internal.LoaderErrors
+
+// Source describes where a configuration value was loaded from
+type Source = internal.Source
+
+// Explanation describes the effective value of a key along with every value it shadows
+type Explanation = internal.Explanation
+
+// Candidate describes a value supplied for a key by a single loader
+type Candidate = internal.Candidate
+
+// ValidationError describes a structure field that breaks one of the rules in its validate tag
+type ValidationError = internal.ValidationError
+
+// ValidationErrors describes a collection of validation failures
+type ValidationErrors = internal.ValidationErrors
+
+// Schema describes a compiled JSON Schema that configurations can be validated against
+type Schema = internal.Schema
+
+// SchemaError describes a location in the configuration that doesn't match a JSON Schema
+type SchemaError = internal.SchemaError
+
+// SchemaErrors describes a collection of schema violations
+type SchemaErrors = internal.SchemaErrors
+
+// InterpolationError describes a reference in a configuration value that couldn't be resolved
+type InterpolationError = internal.InterpolationError
+
+// InterpolationErrors describes a collection of interpolation failures
+type InterpolationErrors = internal.InterpolationErrors
+
+// SecretResolver resolves the reference in a secret reference, such as the path in `file:///run/secrets/db_pass`
+type SecretResolver = internal.SecretResolver
+
+// SecretError describes a secret reference that couldn't be resolved
+type SecretError = internal.SecretError
+
+// KeySource describes a function that supplies the keys used to decrypt configuration values
+type KeySource = internal.KeySource
+
+// Format describes a format a configuration can be written in
+type Format = internal.Format
+
+// DumpOption describes an option that changes how a configuration is dumped
+type DumpOption = internal.DumpOption
+
+// DecodeReport describes the keys that didn't map to any structure field and the fields that no loader set
+type DecodeReport = internal.DecodeReport
+
+// UnusedKey describes a configuration key that didn't map to any structure field
+type UnusedKey = internal.UnusedKey
+
+// UnusedKeysError describes the keys that didn't map to any field when copying to a structure strictly
+type UnusedKeysError = internal.UnusedKeysError
+
+// DecodeHook describes a function that converts a value before it's copied to a structure field
+type DecodeHook = internal.DecodeHook
+
+// Watcher reloads a configuration whenever one of its files changes
+type Watcher = internal.Watcher
+
+// ChangeHandler is called with the old and new value of a changed key
+type ChangeHandler = internal.ChangeHandler
+
+// ConversionError describes a value that couldn't be converted to the requested type
+type ConversionError = internal.ConversionError
+
+// ByteSize describes a number of bytes, which can be loaded from strings like "512MiB" or "10MB"
+type ByteSize = internal.ByteSize
+
+// ParseByteSize parses a byte size string like "512MiB" or "10MB"
+func ParseByteSize(value string) (ByteSize, error) {
+	return internal.ParseByteSize(value)
+}
+
+// PrintableValue converts the durations and other fmt.Stringer values in a value to strings, for printing
+func PrintableValue(value interface{}) interface{} {
+	return internal.PrintableValue(value)
+}
+
+// Get gets a key from a configuration, converting it to the requested type
+func Get[T any](config *internal.Config, key string) (T, error) {
+	return internal.Get[T](config, key)
+}
+
+// GetOr gets a key from a configuration, converting it to the requested type or returning the default if that fails
+func GetOr[T any](config *internal.Config, key string, defaultValue T) T {
+	return internal.GetOr[T](config, key, defaultValue)
+}
+
+// MustGet gets a key from a configuration, converting it to the requested type and panicking if that fails
+func MustGet[T any](config *internal.Config, key string) T {
+	return internal.MustGet[T](config, key)
+}
+
+// WithDelimiter sets the delimiter used to separate the segments of a key path (`:` by default)
+func WithDelimiter(delimiter string) internal.Option {
+	return internal.WithDelimiter(delimiter)
+}
+
+// WithCaseInsensitiveKeys makes key lookups, merging and Set ignore the case of keys
+func WithCaseInsensitiveKeys() internal.Option {
+	return internal.WithCaseInsensitiveKeys()
+}
+
+// WithInterpolation resolves references such as `${server:host}` and `${env:HOME}` in configuration values
+func WithInterpolation() internal.Option {
+	return internal.WithInterpolation()
+}
+
+// WithSecretResolver resolves secret references with the supplied scheme, such as `vault://db/password`
+func WithSecretResolver(scheme string, resolver internal.SecretResolver) internal.Option {
+	return internal.WithSecretResolver(scheme, resolver)
+}
+
+// WithFileSecrets resolves secret references like `file:///run/secrets/db_pass` with the contents of the file
+func WithFileSecrets() internal.Option {
+	return internal.WithFileSecrets()
+}
+
+// ReadSecretFile reads a secret from a file, removing its trailing newline
+func ReadSecretFile(filePath string) (string, error) {
+	return internal.ReadSecretFile(filePath)
+}
+
+// WithSchema attaches a JSON Schema to a configuration, which is checked by Validate and on every reload
+func WithSchema(schema *internal.Schema) internal.Option {
+	return internal.WithSchema(schema)
+}
+
+// ParseSchema compiles a JSON Schema from bytes
+func ParseSchema(data []byte) (*internal.Schema, error) {
+	return internal.ParseSchema(data)
+}
+
+// LoadSchemaFile compiles a JSON Schema from a file
+func LoadSchemaFile(filePath string) (*internal.Schema, error) {
+	return internal.LoadSchemaFile(filePath)
+}
+
+// LoadSchemaFS compiles a JSON Schema from a file in a file system, such as an embed.FS
+func LoadSchemaFS(fileSystem fs.FS, filePath string) (*internal.Schema, error) {
+	return internal.LoadSchemaFS(fileSystem, filePath)
+}
+
+// WithDecodeHooks adds hooks that convert values before they're copied to structure fields
+func WithDecodeHooks(hooks ...internal.DecodeHook) internal.Option {
+	return internal.WithDecodeHooks(hooks...)
+}
+
+// WithSensitivePatterns replaces the patterns that mark keys as sensitive when dumping a configuration
+func WithSensitivePatterns(patterns ...string) internal.DumpOption {
+	return internal.WithSensitivePatterns(patterns...)
+}
+
+// WithSensitiveFields marks the keys of a structure's fields with a `sensitive:"true"` tag as sensitive when dumping a
+// configuration
+func WithSensitiveFields(structure interface{}) internal.DumpOption {
+	return internal.WithSensitiveFields(structure)
+}
+
+// Strict makes copying to a structure fail if the configuration contains keys that don't map to any field
+func Strict() internal.DecodeOption {
+	return internal.Strict()
+}
+
+// WithReport fills a report with the keys that didn't map to any field and the fields that no loader set when copying
+// to a structure
+func WithReport(report *internal.DecodeReport) internal.DecodeOption {
+	return internal.WithReport(report)
+}
+
 // Arguments creates a new command line argument loader
 func Arguments(separator string, prefix string) *internal.ArgumentLoader {
 	return internal.NewArgumentLoader(separator, prefix)
@@ -41,6 +228,46 @@ func YAMLFile(filePath string, parseDurations bool) *internal.YAMLFileLoader {
 	return internal.NewYAMLFileLoader(filePath, parseDurations)
 }
 
+// Optional wraps a loader so that a missing source loads as an empty configuration
+func Optional(loader internal.Loader) *internal.OptionalLoader {
+	return internal.NewOptionalLoader(loader)
+}
+
+// Decrypted wraps a loader so that the encrypted values in its configuration are decrypted with the supplied keys
+func Decrypted(loader internal.Loader, keys internal.KeySource) *internal.DecryptingLoader {
+	return internal.NewDecryptingLoader(loader, keys)
+}
+
+// KeyFromFile reads decryption keys from a file
+func KeyFromFile(filePath string) internal.KeySource {
+	return internal.KeyFromFile(filePath)
+}
+
+// KeyFromEnvironment reads decryption keys from an environment variable
+func KeyFromEnvironment(name string) internal.KeySource {
+	return internal.KeyFromEnvironment(name)
+}
+
+// GenerateKey generates a random, base64 encoded AES key for encrypting values
+func GenerateKey() (string, error) {
+	return internal.GenerateKey()
+}
+
+// EncryptValue encrypts a value at a key path with a base64 encoded AES key, for writing to a configuration file
+func EncryptValue(value string, keyPath string, key string) (string, error) {
+	return internal.EncryptValue(value, keyPath, key)
+}
+
+// EncryptAgeValue encrypts a value at a key path for the supplied age recipients, for writing to a configuration file
+func EncryptAgeValue(value string, keyPath string, recipients ...string) (string, error) {
+	return internal.EncryptAgeValue(value, keyPath, recipients...)
+}
+
+// Defaults creates a new loader for the defaults declared in the `default` tags of a structure's fields
+func Defaults(structure interface{}) *internal.DefaultsLoader {
+	return internal.NewDefaultsLoader(structure)
+}
+
 // Map creates a new map laoder
 func Map(stringMap map[string]interface{}) *internal.MapLoader {
 	return internal.NewMapLoader(stringMap)